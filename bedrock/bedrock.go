@@ -5,21 +5,80 @@ import (
 	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/jeffail/gabs"
 	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
 	"io/ioutil"
-	"log"
+	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Messages printed for a bump Result.
+const (
+	Updated         = "updated successfully"
+	NothingToUpdate = "nothing to update"
+)
+
 type BedrockRepo interface {
-	UpdateWordPressVersion(version string) string
+	UpdateWordPressVersion(version string) Result
 }
 
+// Clock returns the current time. Swap it out to get deterministic
+// changelog headings.
+type Clock func() time.Time
+
+// FixedClock returns a Clock that always reports t.
+func FixedClock(t time.Time) Clock {
+	return func() time.Time {
+		return t
+	}
+}
+
+// DefaultDateLayout is the date format used in changelog headings.
+const DefaultDateLayout = "2006-01-02"
+
+// Defaults for the configurable parts of a repository.
+const (
+	DefaultPackage        = "johnpbloch/wordpress"
+	DefaultChangelog      = "CHANGELOG.md"
+	DefaultUpdateEntry    = "Update to WordPress {{.WordPressVersion}}"
+	DefaultDowngradeEntry = "Downgrade to WordPress {{.WordPressVersion}}"
+)
+
 type BedrockRepoInstance struct {
-	bedrockPath, composerJSONPath, changelogPath string
+	bedrockPath, composerJSONPath, composerLockPath, changelogPath string
+
+	// Now, Location and DateLayout control the date written into new
+	// changelog headings.
+	Now        Clock
+	Location   *time.Location
+	DateLayout string
+
+	// VersionSource is VersionFromChangelog (the default) or
+	// VersionFromTags.
+	VersionSource string
+
+	// Package is the Composer package that installs WordPress.
+	Package string
+
+	// UpdateEntry and DowngradeEntry are the changelog entry templates,
+	// rendered with a BumpInfo.
+	UpdateEntry, DowngradeEntry string
+
+	// AllowDowngrade lets a bump move to an older WordPress release.
+	AllowDowngrade bool
+
+	// Skipped lists intermediate WordPress releases to record under the
+	// version note.
+	Skipped []SkippedRelease
+}
+
+// SkippedRelease is a WordPress release jumped over by a bump.
+type SkippedRelease struct {
+	Version  string
+	Security bool
 }
 
 func check(e error) {
@@ -32,7 +91,14 @@ func NewBedrock(Path string) BedrockRepoInstance {
 	return BedrockRepoInstance{
 		bedrockPath:      Path,
 		composerJSONPath: path.Join(Path, "composer.json"),
-		changelogPath:    path.Join(Path, "CHANGELOG.md"),
+		composerLockPath: path.Join(Path, "composer.lock"),
+		changelogPath:    path.Join(Path, DefaultChangelog),
+		Now:              time.Now,
+		Location:         time.Local,
+		DateLayout:       DefaultDateLayout,
+		Package:          DefaultPackage,
+		UpdateEntry:      DefaultUpdateEntry,
+		DowngradeEntry:   DefaultDowngradeEntry,
 	}
 }
 
@@ -43,28 +109,173 @@ func (b BedrockRepoInstance) GetComposerJson() *gabs.Container {
 	return jsonParsed
 }
 
-func (b BedrockRepoInstance) UpdateComposerJSON(version string) {
-	cmd := exec.Command("composer.phar", "require", "johnpbloch/wordpress", version, "--no-update", "--no-progress")
-	cmd.Dir = b.bedrockPath
-	err := cmd.Run()
+// ComposerCommand is the command line that requires version of WordPress.
+func (b BedrockRepoInstance) ComposerCommand(version string) []string {
+	return []string{"composer.phar", "require", b.pkg(), version, "--no-update", "--no-progress"}
+}
+
+// ComposerError is returned when Composer fails or cannot be run.
+type ComposerError struct {
+	Args   []string
+	Err    error
+	Output string
+}
+
+func (e *ComposerError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", strings.Join(e.Args, " "), e.Err, e.Output)
+}
+
+// RunComposer requires version of WordPress with Composer.
+func (b BedrockRepoInstance) RunComposer(version string) error {
+	return b.runComposerIn(b.bedrockPath, version)
+}
+
+func (b BedrockRepoInstance) runComposerIn(dir, version string) error {
+	args := b.ComposerCommand(version)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return &ComposerError{Args: args, Err: err, Output: strings.TrimSpace(string(out))}
+	}
+	return nil
+}
+
+// requireInTx runs Composer on a copy of composer.json and composer.lock
+// and writes whatever it changed back through tx, so the live files are
+// only ever replaced whole.
+func (b BedrockRepoInstance) requireInTx(version string, tx *Transaction) error {
+	dir, err := ioutil.TempDir("", "bump-bedrock-composer")
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files := []string{b.composerJSONPath, b.composerLockPath}
+	originals := map[string][]byte{}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		originals[f] = data
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(f)), data, 0644); err != nil {
+			return err
+		}
+	}
+	if err := b.runComposerIn(dir, version); err != nil {
+		return err
 	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(f)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if original, ok := originals[f]; ok && string(original) == string(data) {
+			continue
+		}
+		if err := tx.Write(f, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b BedrockRepoInstance) UpdateComposerJSON(version string) {
+	check(b.RunComposer(version))
 }
 
 func (b BedrockRepoInstance) AddVersionNote(lines []string, i int, newWordPressVersion string) []string {
-	lines = append(
-		lines[:i],
-		append(
-			[]string{fmt.Sprintf("* Update to WordPress %s", newWordPressVersion)},
-			lines[i:]...,
-		)...,
-	)
-	return lines
+	entry, err := b.VersionEntry(newWordPressVersion)
+	check(err)
+	return insertNote(lines, i, entry)
+}
+
+// VersionEntry renders the changelog entry for moving to v, using
+// DowngradeEntry when v is an allowed downgrade.
+func (b BedrockRepoInstance) VersionEntry(v string) (string, error) {
+	tmpl := defaultString(b.UpdateEntry, DefaultUpdateEntry)
+	if b.isDowngrade(v) {
+		tmpl = defaultString(b.DowngradeEntry, DefaultDowngradeEntry)
+	}
+	current, _ := b.ReadWordPressVersion()
+	entry, err := RenderTemplate(tmpl, BumpInfo{OldWordPressVersion: current, WordPressVersion: v})
+	return "* " + entry, err
+}
+
+// entrySentinels stand in for the versions when a template is turned into
+// a pattern. They are versions themselves so {{.Action}} still renders
+// "Update" or "Downgrade".
+const (
+	entryLow     = "0.0.0.1111"
+	entryHigh    = "0.0.0.2222"
+	entryProject = "0.0.0.3333"
+)
+
+// IsVersionEntry reports whether note is a changelog entry VersionEntry
+// could have written, with the configured or the default templates.
+func (b BedrockRepoInstance) IsVersionEntry(note string) bool {
+	for _, tmpl := range []struct{ text, old, new string }{
+		{defaultString(b.UpdateEntry, DefaultUpdateEntry), entryLow, entryHigh},
+		{defaultString(b.DowngradeEntry, DefaultDowngradeEntry), entryHigh, entryLow},
+		{DefaultUpdateEntry, entryLow, entryHigh},
+		{DefaultDowngradeEntry, entryHigh, entryLow},
+	} {
+		entry, err := RenderTemplate(tmpl.text, BumpInfo{OldWordPressVersion: tmpl.old, WordPressVersion: tmpl.new, ProjectVersion: entryProject})
+		if err != nil {
+			continue
+		}
+		pattern := regexp.QuoteMeta("* " + entry)
+		for _, sentinel := range []string{entryLow, entryHigh, entryProject} {
+			pattern = strings.Replace(pattern, regexp.QuoteMeta(sentinel), `\S+`, -1)
+		}
+		if regexp.MustCompile("^" + pattern + "$").MatchString(strings.TrimSpace(note)) {
+			return true
+		}
+	}
+	return false
+}
+
+func insertNote(lines []string, i int, note string) []string {
+	return append(lines[:i], append([]string{note}, lines[i:]...)...)
+}
+
+// Today formats the current date for a changelog heading using the
+// configured clock, time zone and layout.
+func (b BedrockRepoInstance) Today() string {
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	loc := b.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	layout := b.DateLayout
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+	return now().In(loc).Format(layout)
+}
+
+// AddSkippedReleases inserts a nested note for every skipped release at
+// line i, oldest first.
+func (b BedrockRepoInstance) AddSkippedReleases(lines []string, i int, skipped []SkippedRelease) []string {
+	notes := []string{}
+	for _, r := range skipped {
+		note := fmt.Sprintf("  * Includes WordPress %s", r.Version)
+		if r.Security {
+			note += " (security release)"
+		}
+		notes = append(notes, note)
+	}
+	return append(lines[:i], append(notes, lines[i:]...)...)
 }
 
 func (b BedrockRepoInstance) AddTitle(lines []string, i int, nextBedrockVersion string) []string {
-	date := time.Now().Format("2006-01-02")
+	date := b.Today()
 
 	title := []string{fmt.Sprintf("### %s: %s", nextBedrockVersion, date), ""}
 
@@ -85,23 +296,38 @@ func (b BedrockRepoInstance) GetCurrentBedrockVersion(lines []string) string {
 	return currentBedrockVersion
 }
 
-func (b BedrockRepoInstance) UpdateChangelog(version string) {
+// NextVersion bumps the patch component of an x.y.z version.
+func NextVersion(current string) string {
+	next := strings.Split(current, ".")
+	patch, _ := strconv.Atoi(next[len(next)-1])
+	patch++
+	next[len(next)-1] = strconv.Itoa(patch)
+	return strings.Join(next, ".")
+}
+
+// NewChangelog returns the changelog contents with an entry for the
+// WordPress version added, without writing them.
+func (b BedrockRepoInstance) NewChangelog(version string) (string, error) {
+	output, _, _, err := b.newChangelog(version)
+	return output, err
+}
+
+// newChangelog also returns the current and next project versions.
+func (b BedrockRepoInstance) newChangelog(version string) (string, string, string, error) {
 	input, err := ioutil.ReadFile(b.changelogPath)
-	check(err)
+	if err != nil {
+		return "", "", "", err
+	}
 
-	var currentBedrockVersion string
-	var nextBedrockVersion string
 	lines := strings.Split(string(input), "\n")
 
 	// find current version
-	currentBedrockVersion = b.GetCurrentBedrockVersion(lines)
+	currentBedrockVersion, err := b.CurrentVersion(lines)
+	if err != nil {
+		return "", "", "", err
+	}
 
-	// calculate bumped version
-	nextBedrockVersionArray := strings.Split(currentBedrockVersion, ".")
-	minorVersion, _ := strconv.Atoi(nextBedrockVersionArray[2])
-	minorVersion++
-	nextBedrockVersionArray[2] = strconv.Itoa(minorVersion)
-	nextBedrockVersion = strings.Join(nextBedrockVersionArray, ".")
+	nextBedrockVersion := NextVersion(currentBedrockVersion)
 
 	// check for HEAD
 	i := 0
@@ -110,25 +336,197 @@ func (b BedrockRepoInstance) UpdateChangelog(version string) {
 	} else {
 		lines = append([]string{""}, lines...)
 	}
-	lines = b.AddVersionNote(lines, i, version)
+	entry, err := b.VersionEntry(version)
+	if err != nil {
+		return "", "", "", err
+	}
+	lines = insertNote(lines, i, entry)
+	lines = b.AddSkippedReleases(lines, i+1, b.Skipped)
 	lines = b.AddTitle(lines, i, nextBedrockVersion)
 
-	output := strings.Join(lines, "\n")
+	return strings.Join(lines, "\n"), currentBedrockVersion, nextBedrockVersion, nil
+}
+
+func (b BedrockRepoInstance) UpdateChangelog(version string) {
+	output, err := b.NewChangelog(version)
+	check(err)
 	err = ioutil.WriteFile(b.changelogPath, []byte(output), 0644)
 	check(err)
 }
 
 func (b BedrockRepoInstance) WordPressVersion() string {
-	value := b.GetComposerJson().Path("require.johnpbloch/wordpress").Data().(string)
+	value := b.GetComposerJson().S("require", b.pkg()).Data().(string)
 	return value
 }
 
-func (b BedrockRepoInstance) UpdateWordPressVersion(v string) string {
-	if version.Compare(b.WordPressVersion(), v, "<") {
-		b.UpdateComposerJSON(v)
-		b.UpdateChangelog(v)
-		return "updated successfully"
-	} else {
-		return "nothing to update"
+// ReadWordPressVersion returns the required WordPress version from
+// composer.json.
+func (b BedrockRepoInstance) ReadWordPressVersion() (string, error) {
+	dat, err := ioutil.ReadFile(b.composerJSONPath)
+	if err != nil {
+		return "", err
+	}
+	jsonParsed, err := gabs.ParseJSON(dat)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", b.composerJSONPath, err)
+	}
+	value, ok := jsonParsed.S("require", b.pkg()).Data().(string)
+	if !ok {
+		return "", fmt.Errorf("%s does not require %s", b.composerJSONPath, b.pkg())
 	}
+	return value, nil
+}
+
+// LockedWordPressVersion returns the WordPress version locked in
+// composer.lock, or "" when the repository has no lock file.
+func (b BedrockRepoInstance) LockedWordPressVersion() (string, error) {
+	dat, err := ioutil.ReadFile(b.composerLockPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	jsonParsed, err := gabs.ParseJSON(dat)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", b.composerLockPath, err)
+	}
+	packages, _ := jsonParsed.S("packages").Children()
+	for _, p := range packages {
+		if name, _ := p.S("name").Data().(string); name == b.pkg() {
+			version, _ := p.S("version").Data().(string)
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not lock %s", b.composerLockPath, b.pkg())
+}
+
+// pkg is the WordPress package name, defaulting to DefaultPackage.
+func (b BedrockRepoInstance) pkg() string {
+	return defaultString(b.Package, DefaultPackage)
+}
+
+// WithChangelog returns b using the changelog at name, relative to the
+// repository unless absolute.
+func (b BedrockRepoInstance) WithChangelog(name string) BedrockRepoInstance {
+	if !filepath.IsAbs(name) {
+		name = path.Join(b.bedrockPath, name)
+	}
+	b.changelogPath = name
+	return b
+}
+
+// InstalledWordPressVersion returns the locked WordPress version, or the
+// composer.json requirement when there is no composer.lock, and the name of
+// the file it came from.
+func (b BedrockRepoInstance) InstalledWordPressVersion() (string, string, error) {
+	locked, err := b.LockedWordPressVersion()
+	if err != nil || locked != "" {
+		return locked, filepath.Base(b.composerLockPath), err
+	}
+	required, err := b.ReadWordPressVersion()
+	return required, filepath.Base(b.composerJSONPath), err
+}
+
+// NeedsUpdate reports whether moving from current to v changes anything:
+// v is newer, or older and downgrades are allowed.
+func (b BedrockRepoInstance) NeedsUpdate(current, v string) bool {
+	return version.Compare(current, v, "<") || b.AllowDowngrade && version.Compare(current, v, ">")
+}
+
+// isDowngrade reports whether v is older than the required WordPress.
+func (b BedrockRepoInstance) isDowngrade(v string) bool {
+	if !b.AllowDowngrade {
+		return false
+	}
+	current, err := b.ReadWordPressVersion()
+	return err == nil && version.Compare(v, current, "<")
+}
+
+// ApplyWordPressUpdate updates composer.json and the changelog inside tx.
+// The changelog is computed before anything is written; on error the
+// caller decides whether to roll tx back.
+func (b BedrockRepoInstance) ApplyWordPressUpdate(v string, tx *Transaction) (Result, error) {
+	r := b.NewResult()
+	current, err := b.ReadWordPressVersion()
+	if err != nil {
+		return r, err
+	}
+	r.OldWordPressVersion, r.NewWordPressVersion = current, current
+
+	if !b.NeedsUpdate(current, v) {
+		changelog, err := b.ReadChangelog()
+		if err != nil {
+			return r, err
+		}
+		r.OldProjectVersion, err = b.CurrentVersion(changelog.Lines)
+		r.NewProjectVersion = r.OldProjectVersion
+		return r, err
+	}
+
+	changelog, oldProjectVersion, newProjectVersion, err := b.newChangelog(v)
+	if err != nil {
+		return r, err
+	}
+	r.OldProjectVersion, r.NewProjectVersion = oldProjectVersion, newProjectVersion
+	if err := b.requireInTx(v, tx); err != nil {
+		return r, err
+	}
+	r.Actions = append(r.Actions, "ran "+strings.Join(b.ComposerCommand(v), " "))
+	if err := tx.Write(b.changelogPath, []byte(changelog)); err != nil {
+		return r, err
+	}
+	r.Actions = append(r.Actions, "updated "+b.rel(b.changelogPath))
+
+	r.Status = StatusUpdated
+	r.NewWordPressVersion = v
+	r.FilesChanged = b.changedFiles(tx)
+	return r, nil
+}
+
+// UpdateWordPressVersion bumps WordPress, restoring every file it touched
+// if any step fails.
+func (b BedrockRepoInstance) UpdateWordPressVersion(v string) Result {
+	tx := NewTransaction()
+	r, err := b.ApplyWordPressUpdate(v, tx)
+	if err != nil {
+		b.Rollback(tx, &r)
+		r.Fail(err)
+	}
+	return r
+}
+
+// Rollback restores the files changed in tx, recording what was restored
+// in r.
+func (b BedrockRepoInstance) Rollback(tx *Transaction, r *Result) {
+	restored, err := tx.Rollback()
+	for _, f := range restored {
+		r.Actions = append(r.Actions, "rolled back "+b.rel(f))
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, "rollback failed: "+err.Error())
+	}
+	r.FilesChanged = b.changedFiles(tx)
+}
+
+// changedFiles lists the files tx has seen that now differ from their
+// snapshot.
+func (b BedrockRepoInstance) changedFiles(tx *Transaction) []string {
+	files := []string{}
+	for _, f := range tx.Changed() {
+		files = append(files, b.rel(f))
+	}
+	return files
+}
+
+// rel makes path relative to the repository where possible.
+func (b BedrockRepoInstance) rel(p string) string {
+	if rel, err := filepath.Rel(b.bedrockPath, p); err == nil {
+		return rel
+	}
+	return p
+}
+
+// Path is the root of the repository.
+func (b BedrockRepoInstance) Path() string {
+	return b.bedrockPath
 }
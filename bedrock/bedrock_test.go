@@ -91,8 +91,8 @@ func TestUpdateWordPressVersion(t *testing.T) {
 		panic(err)
 	}
 	b := NewBedrock(tmpRepo)
-	assert.Equal(t, "nothing to update", b.UpdateWordPressVersion("4.2.1"))
-	assert.Equal(t, "updated successfully", b.UpdateWordPressVersion("100.2.1"))
+	assert.Equal(t, "nothing to update", b.UpdateWordPressVersion("4.2.1").Message())
+	assert.Equal(t, "updated successfully", b.UpdateWordPressVersion("100.2.1").Message())
 }
 
 func TestUpdateChangelog(t *testing.T) {
@@ -143,3 +143,70 @@ func TestAddVersionNote(t *testing.T) {
 
 	assert.Equal(t, "* Update to WordPress 100.100.100", out[0])
 }
+
+func TestAddTitle(t *testing.T) {
+	b := NewBedrock("yee")
+	b.Now = FixedClock(time.Date(2015, 5, 6, 23, 30, 0, 0, time.UTC))
+	b.Location = time.UTC
+
+	out := b.AddTitle([]string{"* Update to WordPress 4.2.2"}, 0, "1.3.7")
+
+	assert.Equal(t, "### 1.3.7: 2015-05-06", out[0])
+	assert.Equal(t, "", out[1])
+	assert.Equal(t, "* Update to WordPress 4.2.2", out[2])
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	check(err)
+	b.Location = tokyo
+	assert.Equal(t, "2015-05-07", b.Today())
+
+	b.DateLayout = "January 2, 2006"
+	assert.Equal(t, "May 7, 2015", b.Today())
+}
+
+func TestAddSkippedReleases(t *testing.T) {
+	b := NewBedrock("yee")
+	lines := []string{"* Update to WordPress 4.2.4", "", "### 1.3.6: 2015-04-27"}
+
+	out := b.AddSkippedReleases(lines, 1, []SkippedRelease{
+		{Version: "4.2.2", Security: true},
+		{Version: "4.2.3"},
+	})
+
+	assert.Equal(t, []string{
+		"* Update to WordPress 4.2.4",
+		"  * Includes WordPress 4.2.2 (security release)",
+		"  * Includes WordPress 4.2.3",
+		"",
+		"### 1.3.6: 2015-04-27",
+	}, out)
+}
+
+func copyFixtures(test string) string {
+	dir := makeTmpDir(test)
+	cpCmd := exec.Command("cp", "-rf", "./fixtures/.", dir)
+	check(cpCmd.Run())
+	return dir
+}
+
+func TestUpdateChangelogSkippedReleases(t *testing.T) {
+	b := NewBedrock(copyFixtures("updateChangelogSkipped"))
+	b.Now = FixedClock(time.Date(2015, 8, 4, 12, 0, 0, 0, time.UTC))
+	b.Skipped = []SkippedRelease{{Version: "4.2.2", Security: true}, {Version: "4.2.3", Security: true}}
+
+	b.UpdateChangelog("4.2.4")
+
+	input, err := ioutil.ReadFile(b.changelogPath)
+	check(err)
+	lines := strings.Split(string(input), "\n")
+
+	assert.Equal(t, []string{
+		"### 1.3.7: 2015-08-04",
+		"",
+		"* Update to WordPress 4.2.4",
+		"  * Includes WordPress 4.2.2 (security release)",
+		"  * Includes WordPress 4.2.3 (security release)",
+		"",
+		"### 1.3.6: 2015-04-27",
+	}, lines[:7])
+}
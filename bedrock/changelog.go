@@ -0,0 +1,169 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Unreleased is the heading used for changes that have not been released yet.
+const Unreleased = "HEAD"
+
+var releaseHeading = regexp.MustCompile(`^### (HEAD|\d+\.\d+\.\d+)(?::\s*(.*))?\s*$`)
+
+// Release is a single "### x.y.z: date" section of a changelog.
+type Release struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date,omitempty"`
+	Line    int      `json:"line"`
+	Notes   []string `json:"notes"`
+}
+
+// Changelog is a parsed CHANGELOG.md. Releases are in file order, newest
+// first.
+type Changelog struct {
+	Lines    []string
+	Releases []Release
+}
+
+// ParseChangelog splits a changelog into its release sections. Text before
+// the first heading is ignored.
+func ParseChangelog(input string) Changelog {
+	c := Changelog{Lines: strings.Split(input, "\n")}
+
+	var current *Release
+	for i, line := range c.Lines {
+		if m := releaseHeading.FindStringSubmatch(line); m != nil {
+			c.Releases = append(c.Releases, Release{
+				Version: m[1],
+				Date:    strings.TrimSpace(m[2]),
+				Line:    i + 1,
+				Notes:   []string{},
+			})
+			current = &c.Releases[len(c.Releases)-1]
+			continue
+		}
+		if current != nil {
+			current.Notes = append(current.Notes, line)
+		}
+	}
+
+	for i := range c.Releases {
+		c.Releases[i].Notes = trimBlankLines(c.Releases[i].Notes)
+	}
+	return c
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Release returns the section for version. An empty version selects the
+// most recent released section, skipping any unreleased HEAD section.
+func (c Changelog) Release(version string) (Release, bool) {
+	for _, r := range c.Releases {
+		if version == "" && r.Version != Unreleased {
+			return r, true
+		}
+		if r.Version == version {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// Markdown renders the body of the release, suitable for a GitHub release.
+func (r Release) Markdown() string {
+	return strings.Join(r.Notes, "\n") + "\n"
+}
+
+// JSON renders the release as an indented JSON document.
+func (r Release) JSON() (string, error) {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// Changelog styles reported by Format.
+const (
+	StyleBedrock = "bedrock"
+	StyleLoose   = "loose"
+	StyleNone    = "none"
+)
+
+// DateLayouts are the heading date layouts Format recognises, most likely
+// first.
+var DateLayouts = []string{
+	DefaultDateLayout,
+	"2006/01/02",
+	"01/02/2006",
+	"02.01.2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// ChangelogFormat describes how a changelog writes its release headings.
+type ChangelogFormat struct {
+	Style      string `json:"style"`
+	DateLayout string `json:"date_layout,omitempty"`
+}
+
+// Format detects the heading style and the date layout of the changelog.
+// The style is StyleBedrock when every heading is "### x.y.z: date",
+// StyleLoose when some only resemble one and StyleNone without headings.
+func (c Changelog) Format() ChangelogFormat {
+	f := ChangelogFormat{Style: StyleNone}
+	dates := []string{}
+	for _, line := range c.Lines {
+		v, date, ok := looseRelease(line)
+		if !ok {
+			continue
+		}
+		if f.Style == StyleNone {
+			f.Style = StyleBedrock
+		}
+		if line != canonicalHeading(v, date) {
+			f.Style = StyleLoose
+		}
+		if date != "" {
+			dates = append(dates, date)
+		}
+	}
+	if len(dates) == 0 {
+		return f
+	}
+	for _, layout := range DateLayouts {
+		matches := true
+		for _, date := range dates {
+			if _, err := time.Parse(layout, date); err != nil {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			f.DateLayout = layout
+			break
+		}
+	}
+	return f
+}
+
+// ReadChangelog parses the changelog of the repository.
+func (b BedrockRepoInstance) ReadChangelog() (Changelog, error) {
+	input, err := ioutil.ReadFile(b.changelogPath)
+	if err != nil {
+		return Changelog{}, err
+	}
+	return ParseChangelog(string(input)), nil
+}
@@ -0,0 +1,58 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseChangelog(t *testing.T) {
+	input, err := ioutil.ReadFile("./fixtures/CHANGELOG-head.md")
+	check(err)
+
+	c := ParseChangelog(string(input))
+
+	assert.Equal(t, Unreleased, c.Releases[0].Version)
+	assert.Equal(t, "", c.Releases[0].Date)
+	assert.Equal(t, []string{"* Some Bullshit"}, c.Releases[0].Notes)
+
+	assert.Equal(t, "1.3.6", c.Releases[1].Version)
+	assert.Equal(t, "2015-04-27", c.Releases[1].Date)
+	assert.Equal(t, 5, c.Releases[1].Line)
+	assert.Equal(t, []string{"* Update to WordPress 4.2.1"}, c.Releases[1].Notes)
+}
+
+func TestChangelogRelease(t *testing.T) {
+	c := ParseChangelog("### HEAD\n\n* wip\n\n### 1.0.1: 2015-01-02\n\n* two\n\n### 1.0.0: 2015-01-01\n\n* one\n")
+
+	latest, ok := c.Release("")
+	assert.True(t, ok)
+	assert.Equal(t, "1.0.1", latest.Version)
+
+	head, ok := c.Release(Unreleased)
+	assert.True(t, ok)
+	assert.Equal(t, "* wip\n", head.Markdown())
+
+	first, ok := c.Release("1.0.0")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"* one"}, first.Notes)
+
+	_, ok = c.Release("9.9.9")
+	assert.False(t, ok)
+}
+
+func TestReleaseJSON(t *testing.T) {
+	r := Release{Version: "1.0.0", Date: "2015-01-01", Line: 1, Notes: []string{"* one"}}
+	out, err := r.JSON()
+	assert.Nil(t, err)
+	assert.Contains(t, out, `"version": "1.0.0"`)
+	assert.Contains(t, out, `"notes": [`)
+}
+
+func TestChangelogFormat(t *testing.T) {
+	assert.Equal(t, ChangelogFormat{Style: StyleBedrock, DateLayout: DefaultDateLayout},
+		ParseChangelog("### HEAD\n\n* x\n\n### 1.3.6: 2015-04-27\n\n* y\n").Format())
+	assert.Equal(t, ChangelogFormat{Style: StyleLoose, DateLayout: "January 2, 2006"},
+		ParseChangelog("## v1.3.6 - April 27, 2015\n\n* y\n").Format())
+	assert.Equal(t, ChangelogFormat{Style: StyleNone}, ParseChangelog("# Changes\n").Format())
+}
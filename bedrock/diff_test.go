@@ -0,0 +1,49 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	assert.Equal(t, "", UnifiedDiff("a", "b", "same\n", "same\n"))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+	to := "zero\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nTEN\neleven\ntwelve\n"
+
+	assert.Equal(t, `--- a/file
++++ b/file
+@@ -1,3 +1,4 @@
++zero
+ one
+ two
+ three
+@@ -7,6 +8,6 @@
+ seven
+ eight
+ nine
+-ten
++TEN
+ eleven
+ twelve
+`, UnifiedDiff("a/file", "b/file", from, to))
+}
+
+func TestUnifiedDiffMergesCloseHunks(t *testing.T) {
+	from := "a\nb\nc\nd\ne\n"
+	to := "A\nb\nc\nd\nE\n"
+
+	assert.Equal(t, `--- x
++++ y
+@@ -1,5 +1,5 @@
+-a
++A
+ b
+ c
+ d
+-e
++E
+`, UnifiedDiff("x", "y", from, to))
+}
@@ -0,0 +1,334 @@
+package bedrock
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Sources for the current project version.
+const (
+	VersionFromChangelog = "changelog"
+	VersionFromTags      = "tags"
+)
+
+var semverTag = regexp.MustCompile(`^[vV]?(\d+\.\d+\.\d+)$`)
+
+// git runs a git command in the repository and returns its output without
+// the trailing newline.
+func (b BedrockRepoInstance) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.bedrockPath
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// GitTags lists the tags of the local repository.
+func (b BedrockRepoInstance) GitTags() ([]string, error) {
+	out, err := b.git("tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return []string{}, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// LatestSemverTag returns the highest x.y.z (optionally v-prefixed) tag,
+// without the prefix, or "" when there is none.
+func LatestSemverTag(tags []string) string {
+	versions := []string{}
+	for _, tag := range tags {
+		if m := semverTag.FindStringSubmatch(strings.TrimSpace(tag)); m != nil {
+			versions = append(versions, m[1])
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	version.Sort(versions)
+	return versions[len(versions)-1]
+}
+
+// CurrentVersion returns the current project version from the configured
+// VersionSource. When reading tags it warns if the changelog disagrees.
+func (b BedrockRepoInstance) CurrentVersion(lines []string) (string, error) {
+	fromChangelog := b.GetCurrentBedrockVersion(lines)
+	if b.VersionSource == "" || b.VersionSource == VersionFromChangelog {
+		return fromChangelog, nil
+	}
+	if b.VersionSource != VersionFromTags {
+		return "", fmt.Errorf("unknown version source %q", b.VersionSource)
+	}
+
+	tags, err := b.GitTags()
+	if err != nil {
+		return "", err
+	}
+	fromTags := LatestSemverTag(tags)
+	if fromTags == "" {
+		return "", fmt.Errorf("no semver tags found in %s", b.bedrockPath)
+	}
+	if fromChangelog != "" && fromChangelog != fromTags {
+		log.Printf("warning: latest tag %s does not match changelog version %s", fromTags, fromChangelog)
+	}
+	return fromTags, nil
+}
+
+// BumpInfo describes a finished bump for message templates, e.g.
+// "Update to WordPress {{.WordPressVersion}}".
+type BumpInfo struct {
+	OldWordPressVersion string
+	WordPressVersion    string
+	ProjectVersion      string
+}
+
+// Action is "Downgrade" when the bump moved to an older WordPress and
+// "Update" otherwise.
+func (i BumpInfo) Action() string {
+	if i.OldWordPressVersion != "" && version.Compare(i.WordPressVersion, i.OldWordPressVersion, "<") {
+		return "Downgrade"
+	}
+	return "Update"
+}
+
+// GitOptions controls what is recorded in git after a bump. Branch,
+// CommitMessage, TagName and TagMessage are text/template strings rendered
+// with a BumpInfo.
+type GitOptions struct {
+	Branch        string
+	Commit        bool
+	CommitMessage string
+	Tag           bool
+	TagName       string
+	TagMessage    string
+
+	// Files are committed along with the files a bump modifies, relative
+	// to the repository.
+	Files []string
+}
+
+// Default git message templates.
+const (
+	DefaultCommitMessage = "{{.Action}} to WordPress {{.WordPressVersion}}"
+	DefaultTagName       = "{{.ProjectVersion}}"
+	DefaultTagMessage    = "{{.ProjectVersion}}: {{.Action}} to WordPress {{.WordPressVersion}}"
+)
+
+// RenderTemplate renders a message template with info.
+func RenderTemplate(tmpl string, info BumpInfo) (string, error) {
+	t, err := template.New("message").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, info); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// BumpedFiles are the files a bump may modify, relative to the repository.
+func (b BedrockRepoInstance) BumpedFiles() []string {
+	files := []string{"composer.json", "composer.lock"}
+	if rel, err := filepath.Rel(b.bedrockPath, b.changelogPath); err == nil {
+		files = append(files, rel)
+	}
+	return files
+}
+
+// DirtyFiles lists the files with uncommitted changes.
+func (b BedrockRepoInstance) DirtyFiles() ([]string, error) {
+	out, err := b.git("status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 3 {
+			file := line[3:]
+			if i := strings.Index(file, " -> "); i >= 0 {
+				file = file[i+4:]
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// CheckClean refuses to continue when files other than the ones a bump
+// touches have uncommitted changes.
+func (b BedrockRepoInstance) CheckClean() error {
+	dirty, err := b.DirtyFiles()
+	if err != nil {
+		return err
+	}
+	bumped := map[string]bool{}
+	for _, f := range b.BumpedFiles() {
+		bumped[f] = true
+	}
+	unrelated := []string{}
+	for _, f := range dirty {
+		if !bumped[f] {
+			unrelated = append(unrelated, f)
+		}
+	}
+	if len(unrelated) > 0 {
+		return fmt.Errorf("working tree has unrelated uncommitted changes: %s", strings.Join(unrelated, ", "))
+	}
+	return nil
+}
+
+// RecordBump creates the branch, commit and tag requested by opts.
+func (b BedrockRepoInstance) RecordBump(opts GitOptions, info BumpInfo) error {
+	if err := b.CommitBump(opts, info); err != nil {
+		return err
+	}
+	return b.TagBump(opts, info)
+}
+
+// CommitBump creates the branch and commit requested by opts. When a step
+// fails, the files it staged are unstaged and the branch it created is
+// deleted again.
+func (b BedrockRepoInstance) CommitBump(opts GitOptions, info BumpInfo) (err error) {
+	if opts.Tag && !opts.Commit {
+		return fmt.Errorf("tagging a bump requires committing it")
+	}
+	if opts.Branch != "" {
+		var branch, from string
+		if branch, err = RenderTemplate(opts.Branch, info); err != nil {
+			return err
+		}
+		if from, err = b.CurrentBranch(); err != nil {
+			return err
+		}
+		if _, err = b.git("checkout", "-b", branch); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				b.git("checkout", from)
+				b.git("branch", "-D", branch)
+			}
+		}()
+	}
+	if !opts.Commit {
+		return nil
+	}
+	staged := false
+	defer func() {
+		if err != nil && staged {
+			b.git("reset", "-q")
+		}
+	}()
+
+	dirty, err := b.DirtyFiles()
+	if err != nil {
+		return err
+	}
+	changed := map[string]bool{}
+	for _, f := range dirty {
+		changed[f] = true
+	}
+	for _, f := range append(b.BumpedFiles(), opts.Files...) {
+		if changed[f] {
+			staged = true
+			if _, err := b.git("add", "--", f); err != nil {
+				return err
+			}
+		}
+	}
+	message, err := RenderTemplate(defaultString(opts.CommitMessage, DefaultCommitMessage), info)
+	if err != nil {
+		return err
+	}
+	_, err = b.git("commit", "-m", message)
+	return err
+}
+
+// TagBump tags the bump commit when opts asks for it.
+func (b BedrockRepoInstance) TagBump(opts GitOptions, info BumpInfo) error {
+	if !opts.Tag {
+		return nil
+	}
+	name, err := RenderTemplate(defaultString(opts.TagName, DefaultTagName), info)
+	if err != nil {
+		return err
+	}
+	message, err := RenderTemplate(defaultString(opts.TagMessage, DefaultTagMessage), info)
+	if err != nil {
+		return err
+	}
+	_, err = b.git("tag", "-a", name, "-m", message)
+	return err
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// CurrentBranch returns the checked out branch.
+func (b BedrockRepoInstance) CurrentBranch() (string, error) {
+	return b.git("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// RemoteURL returns the fetch URL of a remote.
+func (b BedrockRepoInstance) RemoteURL(remote string) (string, error) {
+	return b.git("remote", "get-url", remote)
+}
+
+// Push pushes branch to remote, replacing an earlier push of the same bump
+// branch.
+func (b BedrockRepoInstance) Push(remote, branch string) error {
+	_, err := b.git("push", "--force-with-lease", "--set-upstream", remote, branch)
+	return err
+}
+
+// Commands lists the git commands RecordBump would run for info, staging
+// files.
+func (opts GitOptions) Commands(info BumpInfo, files []string) ([][]string, error) {
+	cmds := [][]string{}
+	if opts.Branch != "" {
+		branch, err := RenderTemplate(opts.Branch, info)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, []string{"git", "checkout", "-b", branch})
+	}
+	if !opts.Commit {
+		return cmds, nil
+	}
+	message, err := RenderTemplate(defaultString(opts.CommitMessage, DefaultCommitMessage), info)
+	if err != nil {
+		return nil, err
+	}
+	cmds = append(cmds,
+		append([]string{"git", "add", "--"}, files...),
+		[]string{"git", "commit", "-m", message},
+	)
+	if !opts.Tag {
+		return cmds, nil
+	}
+	name, err := RenderTemplate(defaultString(opts.TagName, DefaultTagName), info)
+	if err != nil {
+		return nil, err
+	}
+	message, err = RenderTemplate(defaultString(opts.TagMessage, DefaultTagMessage), info)
+	if err != nil {
+		return nil, err
+	}
+	return append(cmds, []string{"git", "tag", "-a", name, "-m", message}), nil
+}
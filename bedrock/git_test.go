@@ -0,0 +1,175 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gitIdentity() {
+	os.Setenv("GIT_AUTHOR_NAME", "test")
+	os.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	os.Setenv("GIT_COMMITTER_NAME", "test")
+	os.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+func makeGitRepo(test string, tags ...string) string {
+	return initGitRepo(makeTmpDir(test), tags...)
+}
+
+func initGitRepo(dir string, tags ...string) string {
+	gitIdentity()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			panic(string(out))
+		}
+	}
+	for _, tag := range tags {
+		cmd := exec.Command("git", "tag", tag)
+		cmd.Dir = dir
+		check(cmd.Run())
+	}
+	return dir
+}
+
+func TestLatestSemverTag(t *testing.T) {
+	assert.Equal(t, "1.10.0", LatestSemverTag([]string{"1.3.7", "v1.10.0", "1.9.2", "nightly", "2.0.0-beta"}))
+	assert.Equal(t, "", LatestSemverTag([]string{"nightly"}))
+	assert.Equal(t, "", LatestSemverTag(nil))
+}
+
+func TestNextVersion(t *testing.T) {
+	assert.Equal(t, "1.3.7", NextVersion("1.3.6"))
+	assert.Equal(t, "1.3.10", NextVersion("1.3.9"))
+}
+
+func TestCurrentVersion(t *testing.T) {
+	lines := strings.Split("### 1.3.6: 2015-04-27\n\n* Update to WordPress 4.2.1", "\n")
+
+	b := NewBedrock(makeGitRepo("currentVersion", "1.3.5", "v1.3.8", "junk"))
+	v, err := b.CurrentVersion(lines)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.3.6", v)
+
+	b.VersionSource = VersionFromTags
+	v, err = b.CurrentVersion(lines)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.3.8", v)
+
+	b = NewBedrock(makeGitRepo("currentVersionNoTags"))
+	b.VersionSource = VersionFromTags
+	_, err = b.CurrentVersion(lines)
+	assert.NotNil(t, err)
+
+	b.VersionSource = "carrier-pigeon"
+	_, err = b.CurrentVersion(lines)
+	assert.NotNil(t, err)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	info := BumpInfo{OldWordPressVersion: "4.2.1", WordPressVersion: "4.2.2", ProjectVersion: "1.3.7"}
+
+	out, err := RenderTemplate(DefaultTagMessage, info)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.3.7: Update to WordPress 4.2.2", out)
+
+	_, err = RenderTemplate("{{.Nope}}", info)
+	assert.NotNil(t, err)
+}
+
+func TestCheckClean(t *testing.T) {
+	b := NewBedrock(initGitRepo(copyFixtures("checkClean")))
+	assert.Nil(t, b.CheckClean())
+
+	check(ioutil.WriteFile(b.composerJSONPath, []byte("{}"), 0644))
+	assert.Nil(t, b.CheckClean())
+
+	check(ioutil.WriteFile(b.bedrockPath+"/wp-config.php", []byte("<?php"), 0644))
+	err := b.CheckClean()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "wp-config.php")
+}
+
+func TestRecordBump(t *testing.T) {
+	b := NewBedrock(initGitRepo(copyFixtures("recordBump")))
+	b.Now = FixedClock(time.Date(2015, 5, 7, 0, 0, 0, 0, time.UTC))
+	b.UpdateChangelog("4.2.2")
+
+	err := b.RecordBump(GitOptions{
+		Branch:        "wp-{{.WordPressVersion}}",
+		Commit:        true,
+		CommitMessage: DefaultCommitMessage,
+		Tag:           true,
+	}, BumpInfo{OldWordPressVersion: "4.2.1", WordPressVersion: "4.2.2", ProjectVersion: "1.3.7"})
+	assert.Nil(t, err)
+
+	branch, _ := b.git("rev-parse", "--abbrev-ref", "HEAD")
+	assert.Equal(t, "wp-4.2.2", branch)
+	subject, _ := b.git("log", "-1", "--format=%s")
+	assert.Equal(t, "Update to WordPress 4.2.2", subject)
+	files, _ := b.git("show", "--name-only", "--format=", "HEAD")
+	assert.Equal(t, "CHANGELOG.md", files)
+	tags, _ := b.GitTags()
+	assert.Equal(t, []string{"1.3.7"}, tags)
+	dirty, _ := b.DirtyFiles()
+	assert.Empty(t, dirty)
+
+	err = b.RecordBump(GitOptions{Tag: true}, BumpInfo{})
+	assert.NotNil(t, err)
+}
+
+func TestCommitBumpUndoesFailure(t *testing.T) {
+	b := NewBedrock(initGitRepo(copyFixtures("commitBumpFails")))
+	b.Now = FixedClock(time.Date(2015, 5, 7, 0, 0, 0, 0, time.UTC))
+	b.UpdateChangelog("4.2.2")
+	from, _ := b.CurrentBranch()
+
+	err := b.CommitBump(GitOptions{
+		Branch:        "wp-{{.WordPressVersion}}",
+		Commit:        true,
+		CommitMessage: "{{.Nope}}",
+	}, BumpInfo{WordPressVersion: "4.2.2"})
+	assert.NotNil(t, err)
+
+	branch, _ := b.CurrentBranch()
+	assert.Equal(t, from, branch)
+	branches, _ := b.git("branch", "--format=%(refname:short)")
+	assert.Equal(t, from, branches)
+	staged, _ := b.git("diff", "--cached", "--name-only")
+	assert.Equal(t, "", staged)
+	dirty, _ := b.DirtyFiles()
+	assert.Equal(t, []string{"CHANGELOG.md"}, dirty)
+}
+
+func TestGitOptionsCommands(t *testing.T) {
+	info := BumpInfo{WordPressVersion: "4.2.2", ProjectVersion: "1.3.7"}
+	cmds, err := GitOptions{Branch: "wp-{{.WordPressVersion}}", Commit: true, Tag: true}.Commands(info, []string{"composer.json", "CHANGELOG.md"})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"git", "checkout", "-b", "wp-4.2.2"},
+		{"git", "add", "--", "composer.json", "CHANGELOG.md"},
+		{"git", "commit", "-m", "Update to WordPress 4.2.2"},
+		{"git", "tag", "-a", "1.3.7", "-m", "1.3.7: Update to WordPress 4.2.2"},
+	}, cmds)
+
+	cmds, err = GitOptions{}.Commands(info, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, cmds)
+}
+
+func TestBumpInfoAction(t *testing.T) {
+	assert.Equal(t, "Update", BumpInfo{OldWordPressVersion: "4.2.1", WordPressVersion: "4.2.2"}.Action())
+	assert.Equal(t, "Downgrade", BumpInfo{OldWordPressVersion: "4.2.2", WordPressVersion: "4.2.1"}.Action())
+	assert.Equal(t, "Update", BumpInfo{WordPressVersion: "4.2.1"}.Action())
+}
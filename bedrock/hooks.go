@@ -0,0 +1,144 @@
+package bedrock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hook stages, in the order a bump runs them.
+const (
+	HookPreCheck   = "pre-check"
+	HookPreWrite   = "pre-write"
+	HookPostWrite  = "post-write"
+	HookPostCommit = "post-commit"
+)
+
+// HookStages lists every hook stage in the order a bump runs them.
+var HookStages = []string{HookPreCheck, HookPreWrite, HookPostWrite, HookPostCommit}
+
+// Hooks are shell commands run at stages of a bump, keyed by stage.
+type Hooks map[string]string
+
+// HookError is a hook command that failed.
+type HookError struct {
+	Stage   string
+	Command string
+	Err     error
+	Output  string
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook %q: %s: %s", e.Stage, e.Command, e.Err, e.Output)
+}
+
+// HookEnv describes the bump in r to a hook run at stage. Versions not
+// known yet at that stage are empty and files are separated by newlines.
+func HookEnv(stage string, r Result) []string {
+	return []string{
+		"BUMP_BEDROCK_HOOK=" + stage,
+		"BUMP_BEDROCK_REPO=" + r.Repo,
+		"BUMP_BEDROCK_OLD_WORDPRESS_VERSION=" + r.OldWordPressVersion,
+		"BUMP_BEDROCK_NEW_WORDPRESS_VERSION=" + r.NewWordPressVersion,
+		"BUMP_BEDROCK_OLD_PROJECT_VERSION=" + r.OldProjectVersion,
+		"BUMP_BEDROCK_NEW_PROJECT_VERSION=" + r.NewProjectVersion,
+		"BUMP_BEDROCK_FILES_CHANGED=" + strings.Join(r.FilesChanged, "\n"),
+	}
+}
+
+// RunHook runs the hook for stage with sh in the repository, if there is
+// one, and reports whether it ran.
+func (b BedrockRepoInstance) RunHook(hooks Hooks, stage string, r Result) (bool, error) {
+	command := hooks[stage]
+	if command == "" {
+		return false, nil
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = b.bedrockPath
+	cmd.Env = append(os.Environ(), HookEnv(stage, r)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return true, &HookError{Stage: stage, Command: command, Err: err, Output: strings.TrimSpace(string(out))}
+	}
+	return true, nil
+}
+
+// RunHookTx runs the hook for stage like RunHook and records in tx every
+// file the hook changes, so rolling tx back undoes the hook as well. It
+// returns the changed files, relative to the repository. Outside a git
+// repository the hook's changes cannot be told apart from others and are
+// not recorded.
+func (b BedrockRepoInstance) RunHookTx(hooks Hooks, stage string, r Result, tx *Transaction) (bool, []string, error) {
+	if hooks[stage] == "" {
+		return false, nil, nil
+	}
+	before, err := b.DirtyFiles()
+	if err != nil {
+		ran, err := b.RunHook(hooks, stage, r)
+		return ran, nil, err
+	}
+	contents := map[string]string{}
+	for _, f := range before {
+		data, _ := ioutil.ReadFile(filepath.Join(b.bedrockPath, f))
+		contents[f] = string(data)
+		if err := tx.Snapshot(filepath.Join(b.bedrockPath, f)); err != nil {
+			return false, nil, err
+		}
+	}
+
+	ran, hookErr := b.RunHook(hooks, stage, r)
+
+	after, err := b.DirtyFiles()
+	if err != nil {
+		return ran, nil, err
+	}
+	changed := []string{}
+	for _, f := range after {
+		if original, ok := contents[f]; ok {
+			if data, _ := ioutil.ReadFile(filepath.Join(b.bedrockPath, f)); string(data) != original {
+				changed = append(changed, f)
+			}
+			continue
+		}
+		// the file was clean, so its original is the committed one
+		committed, err := exec.Command("git", "-C", b.bedrockPath, "show", "HEAD:"+f).Output()
+		tx.Record(filepath.Join(b.bedrockPath, f), committed, err == nil)
+		changed = append(changed, f)
+	}
+	return ran, changed, hookErr
+}
+
+// UndoBump deletes the tag TagBump created and drops the bump commit,
+// keeping its changes in the working tree. When CommitBump created a
+// branch, from is checked out again and the branch deleted. Clear
+// opts.Tag to keep a tag TagBump did not create.
+func (b BedrockRepoInstance) UndoBump(opts GitOptions, info BumpInfo, from string) error {
+	if opts.Tag {
+		name, err := RenderTemplate(defaultString(opts.TagName, DefaultTagName), info)
+		if err != nil {
+			return err
+		}
+		if _, err := b.git("tag", "-d", name); err != nil {
+			return err
+		}
+	}
+	if opts.Commit {
+		if _, err := b.git("reset", "--mixed", "HEAD~1"); err != nil {
+			return err
+		}
+	}
+	if opts.Branch == "" {
+		return nil
+	}
+	branch, err := RenderTemplate(opts.Branch, info)
+	if err != nil {
+		return err
+	}
+	if _, err := b.git("checkout", from); err != nil {
+		return err
+	}
+	_, err = b.git("branch", "-D", branch)
+	return err
+}
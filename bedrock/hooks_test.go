@@ -0,0 +1,30 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRunHook(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-hook")
+	defer os.RemoveAll(dir)
+	b := NewBedrock(dir)
+	r := Result{Repo: dir, OldWordPressVersion: "4.2.1", NewWordPressVersion: "4.2.2", FilesChanged: []string{"composer.json", "CHANGELOG.md"}}
+	hooks := Hooks{HookPostWrite: `printf "%s %s\n%s" "$BUMP_BEDROCK_HOOK" "$BUMP_BEDROCK_NEW_WORDPRESS_VERSION" "$BUMP_BEDROCK_FILES_CHANGED" > hook.out`}
+
+	ran, err := b.RunHook(hooks, HookPreWrite, r)
+	assert.False(t, ran)
+	assert.Nil(t, err)
+
+	ran, err = b.RunHook(hooks, HookPostWrite, r)
+	assert.True(t, ran)
+	assert.Nil(t, err)
+	out, _ := ioutil.ReadFile(dir + "/hook.out")
+	assert.Equal(t, "post-write 4.2.2\ncomposer.json\nCHANGELOG.md", string(out))
+
+	_, err = b.RunHook(Hooks{HookPreCheck: "echo nope >&2; exit 2"}, HookPreCheck, r)
+	assert.Equal(t, &HookError{Stage: HookPreCheck, Command: "echo nope >&2; exit 2", Err: err.(*HookError).Err, Output: "nope"}, err)
+	assert.Equal(t, `pre-check hook "echo nope >&2; exit 2": exit status 2: nope`, err.Error())
+}
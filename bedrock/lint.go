@@ -0,0 +1,160 @@
+package bedrock
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// looseHeading matches anything that looks like it was meant to be a
+// release heading, e.g. "## v1.3.6 - 2015-04-27".
+var looseHeading = regexp.MustCompile(`^#{1,6}\s*[vV]?(\d+\.\d+\.\d+|HEAD)\b\s*[:\-]?\s*(.*?)\s*$`)
+
+// headingSuffix is what may follow the version of a release heading: a
+// date, possibly bracketed or linked, in any of DateLayouts.
+var headingSuffix = regexp.MustCompile(`^[\[(]?(\d|(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\.? \d)`)
+
+// looseRelease returns the version and date of a line that looks like
+// it was meant to be a release heading. Headings that merely start with a
+// version, e.g. "### 2.0.0 migration notes" in a release's notes, are not
+// release headings.
+func looseRelease(line string) (string, string, bool) {
+	m := looseHeading.FindStringSubmatch(line)
+	if m == nil || (m[2] != "" && !headingSuffix.MatchString(m[2])) {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Diagnostic is a single problem found in a changelog.
+type Diagnostic struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Fixable bool   `json:"fixable"`
+}
+
+// Format renders the diagnostic as "file:line: message".
+func (d Diagnostic) Format(file string) string {
+	return fmt.Sprintf("%s:%d: %s", file, d.Line, d.Message)
+}
+
+func canonicalHeading(v, date string) string {
+	if v == Unreleased || date == "" {
+		return "### " + v
+	}
+	return fmt.Sprintf("### %s: %s", v, date)
+}
+
+type lintSection struct {
+	version, date string
+	line          int
+	empty         bool
+}
+
+// LintChangelog checks heading syntax, descending version and date order,
+// date validity, duplicate versions and empty sections. Dates are parsed
+// with layout. Releases sharing a date are not reported: a fix released
+// the same day is normal, Bedrock's own 1.3.3 and 1.3.4 are an example.
+func LintChangelog(input, layout string) []Diagnostic {
+	diags := []Diagnostic{}
+	lines := strings.Split(input, "\n")
+
+	var sections []*lintSection
+	for i, line := range lines {
+		v, date, ok := looseRelease(line)
+		if !ok {
+			if len(sections) > 0 && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+				sections[len(sections)-1].empty = false
+			}
+			continue
+		}
+		if line != canonicalHeading(v, date) {
+			diags = append(diags, Diagnostic{
+				Line:    i + 1,
+				Message: fmt.Sprintf("malformed heading %q, expected %q", line, canonicalHeading(v, date)),
+				Fixable: true,
+			})
+		}
+		sections = append(sections, &lintSection{version: v, date: date, line: i + 1, empty: true})
+	}
+
+	seen := map[string]int{}
+	var prev *lintSection
+	var prevDate time.Time
+	for i, s := range sections {
+		if s.empty {
+			diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("section %s is empty", s.version)})
+		}
+		if s.version == Unreleased {
+			if i != 0 {
+				diags = append(diags, Diagnostic{Line: s.line, Message: "unreleased HEAD section must come first"})
+			}
+			continue
+		}
+
+		if first, ok := seen[s.version]; ok {
+			diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("duplicate version %s, first seen on line %d", s.version, first)})
+		} else {
+			seen[s.version] = s.line
+		}
+
+		if prev != nil && !version.Compare(s.version, prev.version, "<") {
+			diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("version %s is out of order, it should be lower than %s on line %d", s.version, prev.version, prev.line)})
+		}
+
+		if s.date == "" {
+			diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("version %s has no date", s.version)})
+		} else if date, err := time.Parse(layout, s.date); err != nil {
+			diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("invalid date %q", s.date)})
+		} else {
+			if !prevDate.IsZero() && date.After(prevDate) {
+				diags = append(diags, Diagnostic{Line: s.line, Message: fmt.Sprintf("date %s is later than the newer release above it", s.date)})
+			}
+			prevDate = date
+		}
+		prev = s
+	}
+	return diags
+}
+
+// FixChangelog rewrites malformed release headings into canonical form.
+func FixChangelog(input string) string {
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		if v, date, ok := looseRelease(line); ok {
+			lines[i] = canonicalHeading(v, date)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ChangelogPath is the path of the repository's changelog.
+func (b BedrockRepoInstance) ChangelogPath() string {
+	return b.changelogPath
+}
+
+// LintChangelog lints the repository changelog, first applying mechanical
+// fixes to the file when fix is set.
+func (b BedrockRepoInstance) LintChangelog(fix bool) ([]Diagnostic, error) {
+	input, err := ioutil.ReadFile(b.changelogPath)
+	if err != nil {
+		return nil, err
+	}
+	layout := b.DateLayout
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+	if fix {
+		fixed := FixChangelog(string(input))
+		if fixed != string(input) {
+			if err := ioutil.WriteFile(b.changelogPath, []byte(fixed), 0644); err != nil {
+				return nil, err
+			}
+		}
+		input = []byte(fixed)
+	}
+	return LintChangelog(string(input), layout), nil
+}
@@ -0,0 +1,74 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLintChangelogFixture(t *testing.T) {
+	input, err := ioutil.ReadFile("./fixtures/CHANGELOG.md")
+	check(err)
+
+	assert.Empty(t, LintChangelog(string(input), DefaultDateLayout))
+}
+
+func TestLintChangelog(t *testing.T) {
+	input := `### 1.0.1: 2015-01-02
+
+* two
+
+## v1.0.2 - 2015-01-03
+
+* three
+
+### 1.0.0: 2015-13-01
+
+### HEAD
+
+* wip
+
+### 0.9.0
+
+* old
+
+### 0.9.0: 2014-01-01
+
+* dup
+`
+	diags := LintChangelog(input, DefaultDateLayout)
+
+	messages := map[int]string{}
+	for _, d := range diags {
+		messages[d.Line] += d.Message + "\n"
+	}
+
+	assert.Contains(t, messages[5], "malformed heading")
+	assert.Contains(t, messages[5], "out of order")
+	assert.Contains(t, messages[5], "later than")
+	assert.Contains(t, messages[9], "is empty")
+	assert.Contains(t, messages[9], "invalid date")
+	assert.Contains(t, messages[11], "must come first")
+	assert.Contains(t, messages[15], "has no date")
+	assert.Contains(t, messages[19], "duplicate version 0.9.0, first seen on line 15")
+	assert.True(t, diags[0].Fixable)
+}
+
+func TestFixChangelog(t *testing.T) {
+	fixed := FixChangelog("## v1.0.2 - 2015-01-03\n\n* three\n\n###1.0.1: 2015-01-02  \n\n* two\n")
+
+	assert.Equal(t, "### 1.0.2: 2015-01-03\n\n* three\n\n### 1.0.1: 2015-01-02\n\n* two\n", fixed)
+	assert.Empty(t, LintChangelog(fixed, DefaultDateLayout))
+}
+
+func TestLintChangelogNotesHeadings(t *testing.T) {
+	input := "### 2.0.0: 2015-01-03\n\n### 2.0.0 migration notes\n\n* rename things\n\n## v1.0.0 (Jan 2, 2015)\n\n* one\n"
+
+	assert.Equal(t, []Diagnostic{{Line: 7, Message: `malformed heading "## v1.0.0 (Jan 2, 2015)", expected "### 1.0.0: (Jan 2, 2015)"`, Fixable: true}, {Line: 7, Message: `invalid date "(Jan 2, 2015)"`}}, LintChangelog(input, DefaultDateLayout))
+	assert.Equal(t, "### 2.0.0: 2015-01-03\n\n### 2.0.0 migration notes\n\n* rename things\n\n### 1.0.0: (Jan 2, 2015)\n\n* one\n", FixChangelog(input))
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	d := Diagnostic{Line: 3, Message: "section 1.0.0 is empty"}
+	assert.Equal(t, "CHANGELOG.md:3: section 1.0.0 is empty", d.Format("CHANGELOG.md"))
+}
@@ -1,15 +1,18 @@
 package mocks
 
-import "github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/mock"
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/mock"
+	"github.com/austinpray/bump-bedrock/bedrock"
+)
 
 type BedrockRepo struct {
 	mock.Mock
 }
 
-func (m *BedrockRepo) UpdateWordPressVersion(version string) string {
+func (m *BedrockRepo) UpdateWordPressVersion(version string) bedrock.Result {
 	ret := m.Called(version)
 
-	r0 := ret.Get(0).(string)
+	r0 := ret.Get(0).(bedrock.Result)
 
 	return r0
 }
@@ -0,0 +1,92 @@
+package bedrock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+)
+
+// composerRequire matches the requirement of pkg in composer.json.
+func composerRequire(pkg string) *regexp.Regexp {
+	return regexp.MustCompile(`("` + regexp.QuoteMeta(pkg) + `"\s*:\s*")[^"]*(")`)
+}
+
+// FileChange is the old and new contents of a file touched by a bump.
+type FileChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// Diff renders the change as a unified diff relative to root.
+func (f FileChange) Diff(root string) string {
+	name := f.Path
+	if rel, err := filepath.Rel(root, f.Path); err == nil {
+		name = rel
+	}
+	return UnifiedDiff("a/"+name, "b/"+name, f.Old, f.New)
+}
+
+// Plan is everything a bump would do, computed without touching disk.
+type Plan struct {
+	Changes  []FileChange
+	Commands [][]string
+}
+
+// NewComposerJSON returns composer.json with the WordPress requirement set
+// to version, as `composer require --no-update` would leave it.
+func (b BedrockRepoInstance) NewComposerJSON(version string) (string, error) {
+	input, err := ioutil.ReadFile(b.composerJSONPath)
+	if err != nil {
+		return "", err
+	}
+	require := composerRequire(b.pkg())
+	if !require.Match(input) {
+		return "", fmt.Errorf("%s does not require %s", b.composerJSONPath, b.pkg())
+	}
+	return require.ReplaceAllString(string(input), "${1}"+version+"${2}"), nil
+}
+
+// PlanWordPressUpdate computes the file changes and commands
+// UpdateWordPressVersion would make. The plan is empty when there is
+// nothing to update.
+func (b BedrockRepoInstance) PlanWordPressUpdate(v string) (Plan, error) {
+	plan := Plan{Changes: []FileChange{}, Commands: [][]string{}}
+	current, err := b.ReadWordPressVersion()
+	if err != nil {
+		return plan, err
+	}
+	if !b.NeedsUpdate(current, v) {
+		return plan, nil
+	}
+
+	for _, f := range []struct {
+		path   string
+		update func(string) (string, error)
+	}{
+		{b.composerJSONPath, b.NewComposerJSON},
+		{b.changelogPath, b.NewChangelog},
+	} {
+		old, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return plan, err
+		}
+		updated, err := f.update(v)
+		if err != nil {
+			return plan, err
+		}
+		plan.Changes = append(plan.Changes, FileChange{Path: f.path, Old: string(old), New: updated})
+	}
+	plan.Commands = append(plan.Commands, b.ComposerCommand(v))
+	return plan, nil
+}
+
+// Diff renders a unified diff for every changed file.
+func (p Plan) Diff(root string) string {
+	var out string
+	for _, c := range p.Changes {
+		out += c.Diff(root)
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestNewComposerJSON(t *testing.T) {
+	b := NewBedrock("./fixtures")
+	out, err := b.NewComposerJSON("4.2.2")
+	assert.Nil(t, err)
+	assert.Contains(t, out, `"johnpbloch/wordpress": "4.2.2"`)
+	assert.Contains(t, out, `"vlucas/phpdotenv": "~1.0.9",`)
+}
+
+func TestNewComposerJSONPackage(t *testing.T) {
+	b := NewBedrock("./fixtures")
+	b.Package = "roots/wordpress"
+	_, err := b.NewComposerJSON("4.2.2")
+	assert.Equal(t, "fixtures/composer.json does not require roots/wordpress", err.Error())
+	assert.Equal(t, "roots/wordpress", b.ComposerCommand("4.2.2")[2])
+}
+
+func TestPlanWordPressUpdate(t *testing.T) {
+	b := NewBedrock("./fixtures")
+	b.Now = FixedClock(time.Date(2015, 5, 7, 0, 0, 0, 0, time.UTC))
+	composerBefore, _ := ioutil.ReadFile("./fixtures/composer.json")
+
+	plan, err := b.PlanWordPressUpdate("4.2.2")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(plan.Changes))
+	assert.Equal(t, [][]string{b.ComposerCommand("4.2.2")}, plan.Commands)
+
+	out := plan.Diff("./fixtures")
+	assert.Contains(t, out, "--- a/composer.json\n+++ b/composer.json\n")
+	assert.Contains(t, out, "-    \"johnpbloch/wordpress\": \"4.2.1\"\n+    \"johnpbloch/wordpress\": \"4.2.2\"\n")
+	assert.Contains(t, out, "--- a/CHANGELOG.md\n+++ b/CHANGELOG.md\n@@ -1,3 +1,7 @@\n+### 1.3.7: 2015-05-07\n+\n+* Update to WordPress 4.2.2\n+\n")
+
+	composerAfter, _ := ioutil.ReadFile("./fixtures/composer.json")
+	assert.Equal(t, string(composerBefore), string(composerAfter))
+
+	plan, err = b.PlanWordPressUpdate("4.2.1")
+	assert.Nil(t, err)
+	assert.Empty(t, plan.Changes)
+}
@@ -0,0 +1,54 @@
+package bedrock
+
+// Statuses of a bump Result.
+const (
+	StatusUpdated = "updated"
+	StatusCurrent = "current"
+	StatusFailed  = "failed"
+)
+
+// Result describes what a bump did to a repository. Files are relative to
+// the repository.
+type Result struct {
+	Repo                string   `json:"repo"`
+	Status              string   `json:"status"`
+	DryRun              bool     `json:"dry_run"`
+	OldWordPressVersion string   `json:"old_wordpress_version"`
+	NewWordPressVersion string   `json:"new_wordpress_version"`
+	OldProjectVersion   string   `json:"old_project_version"`
+	NewProjectVersion   string   `json:"new_project_version"`
+	FilesChanged        []string `json:"files_changed"`
+	Actions             []string `json:"actions"`
+	ChangeRequestURL    string   `json:"change_request_url,omitempty"`
+	ReleaseNotes        string   `json:"release_notes,omitempty"`
+	Diff                string   `json:"diff,omitempty"`
+	Errors              []string `json:"errors"`
+}
+
+// NewResult is an empty result for the repository, in the current state.
+func (b BedrockRepoInstance) NewResult() Result {
+	return Result{
+		Repo:         b.bedrockPath,
+		Status:       StatusCurrent,
+		FilesChanged: []string{},
+		Actions:      []string{},
+		Errors:       []string{},
+	}
+}
+
+// Message is the one line summary printed after a bump.
+func (r Result) Message() string {
+	switch r.Status {
+	case StatusUpdated:
+		return Updated
+	case StatusCurrent:
+		return NothingToUpdate
+	}
+	return "update failed"
+}
+
+// Fail marks the result failed because of err.
+func (r *Result) Fail(err error) {
+	r.Status = StatusFailed
+	r.Errors = append(r.Errors, err.Error())
+}
@@ -0,0 +1,130 @@
+package bedrock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Transaction records the original contents of every file a bump touches
+// so a failed bump can be rolled back. Writes go through a temporary file
+// and a rename, so a file is never left half written.
+type Transaction struct {
+	originals map[string][]byte
+	existed   map[string]bool
+	order     []string
+}
+
+func NewTransaction() *Transaction {
+	return &Transaction{
+		originals: map[string][]byte{},
+		existed:   map[string]bool{},
+	}
+}
+
+// Snapshot remembers the current contents of path, if not already known.
+func (t *Transaction) Snapshot(path string) error {
+	if _, ok := t.existed[path]; ok {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.existed[path] = false
+	} else if err != nil {
+		return err
+	} else {
+		t.existed[path] = true
+		t.originals[path] = contents
+	}
+	t.order = append(t.order, path)
+	return nil
+}
+
+// Record remembers contents as the original of path, if not already known,
+// for a file that has changed since it was last seen. Without existed the
+// file did not exist.
+func (t *Transaction) Record(path string, contents []byte, existed bool) {
+	if _, ok := t.existed[path]; ok {
+		return
+	}
+	t.existed[path] = existed
+	if existed {
+		t.originals[path] = contents
+	}
+	t.order = append(t.order, path)
+}
+
+// Write snapshots path and atomically replaces its contents.
+func (t *Transaction) Write(path string, data []byte) error {
+	if err := t.Snapshot(path); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data)
+}
+
+// Changed lists the snapshotted files whose contents now differ from the
+// snapshot, in the order they were first seen.
+func (t *Transaction) Changed() []string {
+	changed := []string{}
+	for _, path := range t.order {
+		current, err := ioutil.ReadFile(path)
+		if t.existed[path] != (err == nil) || string(current) != string(t.originals[path]) {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// Rollback restores every snapshotted file that changed and returns their
+// paths.
+func (t *Transaction) Rollback() ([]string, error) {
+	restored := []string{}
+	for i := len(t.order) - 1; i >= 0; i-- {
+		path := t.order[i]
+		current, err := ioutil.ReadFile(path)
+		if !t.existed[path] {
+			if err == nil {
+				if err := os.Remove(path); err != nil {
+					return restored, err
+				}
+				restored = append(restored, path)
+			}
+			continue
+		}
+		if err == nil && string(current) == string(t.originals[path]) {
+			continue
+		}
+		if err := WriteFileAtomic(path, t.originals[path]); err != nil {
+			return restored, err
+		}
+		restored = append(restored, path)
+	}
+	return restored, nil
+}
+
+// WriteFileAtomic writes data next to path and renames it into place,
+// keeping the file mode of an existing file.
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".bump-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,103 @@
+package bedrock
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// fakeComposer puts a composer.phar on PATH that rewrites the required
+// version in composer.json and then exits with status.
+func fakeComposer(test, status string) {
+	dir := makeTmpDir(test + "-bin")
+	script := "#!/bin/sh\nsed -i \"s#\\\"$2\\\": \\\"[^\\\"]*\\\"#\\\"$2\\\": \\\"$3\\\"#\" composer.json\nexit " + status + "\n"
+	check(ioutil.WriteFile(path.Join(dir, "composer.phar"), []byte(script), 0755))
+	os.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+}
+
+func TestTransactionRollback(t *testing.T) {
+	dir := makeTmpDir("transaction")
+	existing := path.Join(dir, "CHANGELOG.md")
+	created := path.Join(dir, "composer.lock")
+	check(ioutil.WriteFile(existing, []byte("old"), 0600))
+
+	tx := NewTransaction()
+	assert.Nil(t, tx.Write(existing, []byte("new")))
+	assert.Nil(t, tx.Write(created, []byte("{}")))
+	assert.Nil(t, tx.Write(existing, []byte("newer")))
+
+	contents, _ := ioutil.ReadFile(existing)
+	assert.Equal(t, "newer", string(contents))
+	info, _ := os.Stat(existing)
+	assert.Equal(t, os.FileMode(0600), info.Mode())
+
+	restored, err := tx.Rollback()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{created, existing}, restored)
+
+	contents, _ = ioutil.ReadFile(existing)
+	assert.Equal(t, "old", string(contents))
+	_, err = os.Stat(created)
+	assert.True(t, os.IsNotExist(err))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files), "no temporary files are left behind")
+}
+
+func TestTransactionRollbackUnchanged(t *testing.T) {
+	tx := NewTransaction()
+	check(tx.Snapshot("./fixtures/composer.json"))
+	restored, err := tx.Rollback()
+	assert.Nil(t, err)
+	assert.Empty(t, restored)
+}
+
+func TestApplyWordPressUpdate(t *testing.T) {
+	fakeComposer("applyWordPressUpdate", "0")
+	b := NewBedrock(copyFixtures("applyWordPressUpdate"))
+
+	tx := NewTransaction()
+	result, err := b.ApplyWordPressUpdate("4.2.2", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusUpdated, result.Status)
+	assert.Equal(t, "4.2.1", result.OldWordPressVersion)
+	assert.Equal(t, "1.3.7", result.NewProjectVersion)
+	assert.Equal(t, []string{"composer.json", "CHANGELOG.md"}, result.FilesChanged)
+	assert.Equal(t, "4.2.2", b.WordPressVersion())
+
+	result, err = b.ApplyWordPressUpdate("4.2.2", NewTransaction())
+	assert.Nil(t, err)
+	assert.Equal(t, StatusCurrent, result.Status)
+	assert.Equal(t, "1.3.7", result.OldProjectVersion)
+}
+
+func TestApplyWordPressUpdateRollback(t *testing.T) {
+	fakeComposer("applyWordPressUpdateRollback", "1")
+	b := NewBedrock(copyFixtures("applyWordPressUpdateRollback"))
+
+	tx := NewTransaction()
+	_, err := b.ApplyWordPressUpdate("4.2.2", tx)
+	assert.NotNil(t, err)
+	assert.Equal(t, "4.2.1", b.WordPressVersion(), "composer works on a copy")
+
+	restored, err := tx.Rollback()
+	assert.Nil(t, err)
+	assert.Empty(t, restored)
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+}
+
+func TestUpdateWordPressVersionFailure(t *testing.T) {
+	fakeComposer("updateWordPressVersionFailure", "1")
+	b := NewBedrock(copyFixtures("updateWordPressVersionFailure"))
+
+	result := b.UpdateWordPressVersion("4.2.2")
+
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, "update failed", result.Message())
+	assert.Empty(t, result.Actions)
+	assert.Empty(t, result.FilesChanged)
+	assert.Equal(t, 1, len(result.Errors))
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+}
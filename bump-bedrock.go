@@ -2,12 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/codegangsta/cli"
 	"github.com/austinpray/bump-bedrock/bedrock"
+	"github.com/austinpray/bump-bedrock/config"
+	"github.com/austinpray/bump-bedrock/forge"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 )
 
 type Commit struct {
@@ -24,38 +34,461 @@ type Tag struct {
 
 type Tags []Tag
 
-func GetWordPressTags(url string) Tags {
-	response, err := http.Get(url)
+// GetWordPressTags fetches every WordPress tag, newest first, following
+// the pages of the GitHub API. Every failure is a *NetworkError.
+func GetWordPressTags(url string) (Tags, error) {
 	res := Tags{}
+	next := withPerPage(url)
+	for next != "" {
+		page, link, err := getTagsPage(next)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page...)
+		next = nextPage(link)
+	}
+	if len(res) == 0 {
+		return nil, &NetworkError{fmt.Errorf("GET %s: no tags", url)}
+	}
+	return res, nil
+}
+
+// getTagsPage fetches one page of tags and its Link header.
+func getTagsPage(url string) (Tags, string, error) {
+	page := Tags{}
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, "", &NetworkError{err}
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, "", &NetworkError{fmt.Errorf("GET %s: %s", url, response.Status)}
+	}
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", &NetworkError{err}
+	}
+	if err := json.Unmarshal(contents, &page); err != nil {
+		return nil, "", &NetworkError{fmt.Errorf("GET %s: %s", url, err)}
+	}
+	return page, response.Header.Get("Link"), nil
+}
+
+// withPerPage asks for the largest page GitHub serves unless the URL
+// already picks a page size.
+func withPerPage(rawurl string) string {
+	u, err := url.Parse(rawurl)
 	if err != nil {
-		fmt.Printf("%s", err)
-		os.Exit(1)
-	} else {
-		defer response.Body.Close()
-		contents, err := ioutil.ReadAll(response.Body)
+		return rawurl
+	}
+	q := u.Query()
+	if q.Get("per_page") == "" {
+		q.Set("per_page", "100")
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+var nextLink = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// nextPage is the rel="next" URL of a Link header, or "" on the last page.
+func nextPage(link string) string {
+	if m := nextLink.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func Bump(b bedrock.BedrockRepo, newWordPressVersion string) bedrock.Result {
+	result := b.UpdateWordPressVersion(newWordPressVersion)
+	fmt.Println(result.Message())
+	return result
+}
+
+// ReleaseNotes renders the changelog section for version as "markdown" or
+// "json". An empty version selects the latest release.
+func ReleaseNotes(b bedrock.BedrockRepoInstance, version, format string) (string, error) {
+	changelog, err := b.ReadChangelog()
+	if err != nil {
+		return "", err
+	}
+	release, ok := changelog.Release(version)
+	if !ok {
+		return "", usageErrorf("no changelog section for version %q", version)
+	}
+	switch format {
+	case "", "markdown", "md":
+		return release.Markdown(), nil
+	case "json":
+		return release.JSON()
+	}
+	return "", usageErrorf("unknown release notes format %q", format)
+}
+
+// WriteReleaseNotes writes notes to dest, where "-" means stdout.
+func WriteReleaseNotes(notes, dest string) error {
+	if dest == "-" {
+		_, err := fmt.Print(notes)
+		return err
+	}
+	return ioutil.WriteFile(dest, []byte(notes), 0644)
+}
+
+// ConfigureDates applies the --date, --timezone and --date-layout options
+// to b. An empty date means "today" in the given time zone.
+func ConfigureDates(b bedrock.BedrockRepoInstance, date, timezone, layout string) (bedrock.BedrockRepoInstance, error) {
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return b, fmt.Errorf("invalid timezone %q: %s", timezone, err)
+		}
+		b.Location = loc
+	}
+	if layout != "" {
+		b.DateLayout = layout
+	}
+	if date != "" {
+		t, err := time.ParseInLocation(bedrock.DefaultDateLayout, date, b.Location)
 		if err != nil {
-			fmt.Printf("%s", err)
-			os.Exit(1)
+			return b, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
 		}
-		json.Unmarshal([]byte(contents), &res)
+		b.Now = bedrock.FixedClock(t)
 	}
-	return res
+	return b, nil
 }
 
-func Bump(b bedrock.BedrockRepo, newWordPressVersion string) {
-	fmt.Println(b.UpdateWordPressVersion(newWordPressVersion))
+// NewBumpInfo describes a finished bump, reading the new project version
+// from the changelog.
+func NewBumpInfo(b bedrock.BedrockRepoInstance, oldWordPressVersion, newWordPressVersion string) (bedrock.BumpInfo, error) {
+	changelog, err := b.ReadChangelog()
+	if err != nil {
+		return bedrock.BumpInfo{}, err
+	}
+	release, _ := changelog.Release("")
+	return bedrock.BumpInfo{
+		OldWordPressVersion: oldWordPressVersion,
+		WordPressVersion:    newWordPressVersion,
+		ProjectVersion:      release.Version,
+	}, nil
+}
+
+// DefaultPullRequestBranch is the branch template used by --pr when no
+// --branch is given.
+const DefaultPullRequestBranch = "wordpress-{{.WordPressVersion}}"
+
+// PullRequestOptions configures the change request opened after a bump.
+// Title is a message template. Forge, API, Token and Repo select the
+// hosting service, see forge.New; Repo defaults to the remote's.
+type PullRequestOptions struct {
+	Remote    string
+	Base      string
+	Title     string
+	Labels    []string
+	Reviewers []string
+	Forge     string
+	API       string
+	Token     string
+	Repo      string
+}
+
+// OpenPullRequest pushes the current branch and opens or updates a pull or
+// merge request with the new changelog section as its body.
+func OpenPullRequest(b bedrock.BedrockRepoInstance, opts PullRequestOptions, info bedrock.BumpInfo) (string, error) {
+	branch, err := b.CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	if err := b.Push(opts.Remote, branch); err != nil {
+		return "", &NetworkError{err}
+	}
+	if opts.Repo == "" {
+		remote, err := b.RemoteURL(opts.Remote)
+		if err != nil {
+			return "", err
+		}
+		if opts.Repo, err = forge.RepoFromRemote(remote); err != nil {
+			return "", err
+		}
+	}
+	f, err := forge.New(opts.Forge, opts.API, opts.Token, opts.Repo)
+	if err != nil {
+		return "", err
+	}
+	body, err := ReleaseNotes(b, "", "markdown")
+	if err != nil {
+		return "", err
+	}
+	title, err := bedrock.RenderTemplate(opts.Title, info)
+	if err != nil {
+		return "", err
+	}
+	url, err := f.OpenChangeRequest(forge.ChangeRequest{
+		Title:     title,
+		Body:      body,
+		Head:      branch,
+		Base:      opts.Base,
+		Labels:    opts.Labels,
+		Reviewers: opts.Reviewers,
+	})
+	if err != nil {
+		return "", &NetworkError{err}
+	}
+	return url, nil
+}
+
+// splitList splits a comma separated flag value.
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// LintChangelog prints a diagnostic per problem found in the changelog and
+// returns how many there were.
+func LintChangelog(b bedrock.BedrockRepoInstance, fix bool, format string) (int, error) {
+	diags, err := b.LintChangelog(fix)
+	if err != nil {
+		return 0, err
+	}
+	text := ""
+	for _, d := range diags {
+		text += d.Format(b.ChangelogPath()) + "\n"
+	}
+	return len(diags), Print(format, text, map[string]interface{}{
+		"file":        b.ChangelogPath(),
+		"diagnostics": diags,
+	})
+}
+
+// defaultPath is path, or the working directory when path is empty.
+func defaultPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
 }
 
 func GetVersion(tags Tags) {
 	fmt.Println(tags[0].Name)
 }
 
+// bumpFlags are the flags of bump, shared with fleet bump.
+var bumpFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print the diffs and commands of the bump without changing anything",
+	},
+	cli.StringFlag{
+		Name:  "to",
+		Usage: "bump to this WordPress release instead of the latest",
+	},
+	cli.BoolFlag{
+		Name:  "allow-downgrade",
+		Usage: "allow --to to name an older release than the current one",
+	},
+	cli.StringFlag{
+		Name:  "date",
+		Usage: "date for the changelog heading (YYYY-MM-DD), defaults to today",
+	},
+	cli.StringFlag{
+		Name:   "timezone",
+		Usage:  "time zone used to compute today's date, e.g. UTC",
+		EnvVar: "BUMP_BEDROCK_TIMEZONE",
+	},
+	cli.StringFlag{
+		Name:  "date-layout",
+		Value: bedrock.DefaultDateLayout,
+		Usage: "Go time layout for the changelog heading date",
+	},
+	cli.StringFlag{
+		Name:  "version-source",
+		Value: bedrock.VersionFromChangelog,
+		Usage: "where to read the current project version from: changelog or tags",
+	},
+	cli.BoolFlag{
+		Name:  "record-skipped",
+		Usage: "list every intermediate WordPress release in the changelog entry",
+	},
+	cli.StringFlag{
+		Name:   "security-releases",
+		Usage:  "comma separated WordPress versions to mark as security releases",
+		EnvVar: "BUMP_BEDROCK_SECURITY_RELEASES",
+	},
+	cli.StringFlag{
+		Name:  "branch",
+		Usage: "create a branch named from this template, e.g. wp-{{.WordPressVersion}}",
+	},
+	cli.BoolFlag{
+		Name:  "commit",
+		Usage: "commit composer.json and the changelog after bumping",
+	},
+	cli.StringFlag{
+		Name:  "commit-message",
+		Value: bedrock.DefaultCommitMessage,
+		Usage: "commit message template",
+	},
+	cli.BoolFlag{
+		Name:  "tag",
+		Usage: "tag the bump commit with the new project version",
+	},
+	cli.StringFlag{
+		Name:  "tag-name",
+		Value: bedrock.DefaultTagName,
+		Usage: "tag name template",
+	},
+	cli.StringFlag{
+		Name:  "tag-message",
+		Value: bedrock.DefaultTagMessage,
+		Usage: "annotated tag message template",
+	},
+	cli.BoolFlag{
+		Name:  "pr",
+		Usage: "push the bump branch and open a pull or merge request (implies --commit)",
+	},
+	cli.StringFlag{
+		Name:  "pr-base",
+		Value: "master",
+		Usage: "branch the pull request should merge into",
+	},
+	cli.StringFlag{
+		Name:  "pr-title",
+		Value: bedrock.DefaultCommitMessage,
+		Usage: "pull request title template",
+	},
+	cli.StringFlag{
+		Name:  "pr-labels",
+		Usage: "comma separated labels to add to the pull request",
+	},
+	cli.StringFlag{
+		Name:  "pr-reviewers",
+		Usage: "comma separated users to request reviews from",
+	},
+	cli.StringFlag{
+		Name:  "remote",
+		Value: "origin",
+		Usage: "git remote to push the bump branch to",
+	},
+	cli.StringFlag{
+		Name:  "forge",
+		Value: forge.KindGitHub,
+		Usage: "hosting service for --pr: github, gitlab or gitea",
+	},
+	cli.StringFlag{
+		Name:   "forge-url, github-api",
+		Usage:  "API base URL (GitHub) or server URL (GitLab, Gitea)",
+		EnvVar: "FORGE_URL,GITHUB_API_URL",
+	},
+	cli.StringFlag{
+		Name:   "forge-token, github-token",
+		Usage:  "API token for the hosting service",
+		EnvVar: "FORGE_TOKEN,GITHUB_TOKEN",
+	},
+	cli.StringFlag{
+		Name:  "forge-repo, github-repo",
+		Usage: "owner/name of the repository, defaults to the remote's",
+	},
+	cli.StringFlag{
+		Name:  "release-notes",
+		Usage: "write the new changelog section to a file, or - for stdout with the status on stderr",
+	},
+	cli.StringFlag{
+		Name:  "release-notes-format",
+		Value: "markdown",
+		Usage: "release notes format: markdown or json",
+	},
+}
+
+// NewBumpOptions builds the options of a bump from the command's flags and
+// the repository's configuration.
+func NewBumpOptions(c *cli.Context, cfg config.Config) BumpOptions {
+	opts := BumpOptions{
+		To:             c.String("to"),
+		AllowDowngrade: c.Bool("allow-downgrade"),
+		Policy:         NewPolicy(cfg),
+		Hooks:          NewHooks(cfg),
+		Git: bedrock.GitOptions{
+			Branch:        cfg.Get("branch"),
+			Commit:        cfg.Bool("commit"),
+			CommitMessage: cfg.Get("commit-message"),
+			Tag:           cfg.Bool("tag"),
+			TagName:       cfg.Get("tag-name"),
+			TagMessage:    cfg.Get("tag-message"),
+		},
+		RecordSkipped:      cfg.Bool("record-skipped"),
+		Security:           ParseSecurityReleases(cfg.Get("security-releases")),
+		ReleaseNotes:       c.String("release-notes"),
+		ReleaseNotesFormat: c.String("release-notes-format"),
+		DryRun:             c.Bool("dry-run"),
+	}
+	if c.Bool("pr") {
+		opts.PullRequest = &PullRequestOptions{
+			Remote:    cfg.Get("remote"),
+			Base:      cfg.Get("pr-base"),
+			Title:     cfg.Get("pr-title"),
+			Labels:    cfg.List("pr-labels"),
+			Reviewers: cfg.List("pr-reviewers"),
+			Forge:     cfg.Get("forge"),
+			API:       cfg.Get("forge-url"),
+			Token:     c.String("forge-token"),
+			Repo:      cfg.Get("forge-repo"),
+		}
+	}
+	return opts
+}
+
+// fleetBumper bumps a fleet repository to tags, or to release when it is
+// set, with its own configuration and the command's flags.
+func fleetBumper(c *cli.Context, tags Tags, release string) FleetBumper {
+	return func(repo ManifestRepo) (bedrock.Result, error) {
+		cfg, err := LoadConfig(repo.Path, c)
+		if err != nil {
+			return bedrock.NewBedrock(repo.Path).NewResult(), err
+		}
+		b, err := ConfigureRepo(repo.Path, cfg, c.String("date"))
+		if err != nil {
+			return b.NewResult(), err
+		}
+		opts := NewBumpOptions(c, cfg)
+		opts.Release = release
+		return RunBump(b, tags, opts)
+	}
+}
+
 func main() {
+	defer exitOnPanic(os.Exit)
+	os.Exit(runApp(newApp(), os.Args))
+}
 
-	var APITagsUrl string = "https://api.github.com/repos/johnpbloch/wordpress/tags"
+// runApp runs app and returns ExitFailure when the cli package rejected
+// the arguments: an unknown command, an unknown flag or a bad flag value.
+// Commands exit with their own codes.
+func runApp(app *cli.App, args []string) int {
+	unknown := false
+	app.CommandNotFound = func(c *cli.Context, command string) {
+		PrintError(c.GlobalString("output"), usageErrorf("unknown command %q", command))
+		unknown = true
+	}
+	if err := app.Run(args); err != nil || unknown {
+		return ExitFailure
+	}
+	return ExitUpdated
+}
 
+// newApp builds the command line interface.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Usage = "bump that bedrock version son"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Value: OutputText,
+			Usage: "output format: text or json",
+		},
+	}
 
 	app.Commands = []cli.Command{
 		{
@@ -63,20 +496,512 @@ func main() {
 			Aliases: []string{"getv"},
 			Usage:   "Get the most recent WordPress version",
 			Action: func(c *cli.Context) {
-				GetVersion(GetWordPressTags(APITagsUrl))
+				_, cfg := openRepo(c, ".", "")
+				tags := fetchTags(c, cfg)
+				if c.GlobalString("output") == OutputJSON {
+					Print(OutputJSON, "", map[string]string{"version": tags[0].Name})
+					return
+				}
+				GetVersion(tags)
 			},
 		},
 		{
 			Name:  "bump",
 			Usage: "Execute a bump. Update Changelog, Composer.json",
+			Flags: bumpFlags,
+			Action: func(c *cli.Context) {
+				b, cfg := openRepo(c, c.Args().First(), c.String("date"))
+				opts := NewBumpOptions(c, cfg)
+				result, err := RunBump(b, fetchTags(c, cfg), opts)
+				PrintBump(c.GlobalString("output"), result)
+				if err != nil && c.GlobalString("output") != OutputJSON {
+					fmt.Println(err)
+				}
+				os.Exit(BumpExitCode(result, err))
+			},
+		},
+		{
+			Name:  "fleet",
+			Usage: "Work on many repositories at once",
+			Subcommands: []cli.Command{
+				{
+					Name:  "bump",
+					Usage: "Bump every repository of a manifest to the latest WordPress",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "manifest, m",
+							Value: "sites.json",
+							Usage: "JSON manifest listing the repositories",
+						},
+						cli.IntFlag{
+							Name:  "workers, j",
+							Value: 4,
+							Usage: "number of repositories bumped at the same time",
+						},
+					}, bumpFlags...),
+					Action: func(c *cli.Context) {
+						manifest, err := ReadManifest(c.String("manifest"))
+						if err != nil {
+							PrintError(c.GlobalString("output"), err)
+							os.Exit(ExitFailure)
+						}
+						_, cfg := openRepo(c, ".", "")
+						tags := fetchTags(c, cfg)
+						result := RunFleet(manifest.Repos, c.Int("workers"), fleetBumper(c, tags, ""))
+						Print(c.GlobalString("output"), FleetText(result), result)
+						os.Exit(FleetExitCode(result))
+					},
+				},
+			},
+		},
+		{
+			Name:  "watch",
+			Usage: "Poll for new WordPress releases and bump every repository of a manifest",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "manifest, m",
+					Value: "sites.json",
+					Usage: "JSON manifest listing the repositories",
+				},
+				cli.IntFlag{
+					Name:  "workers, j",
+					Value: 4,
+					Usage: "number of repositories bumped at the same time",
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Value: time.Hour,
+					Usage: "time between polls",
+				},
+				cli.DurationFlag{
+					Name:  "jitter",
+					Value: 5 * time.Minute,
+					Usage: "vary each wait by up to this much",
+				},
+				cli.DurationFlag{
+					Name:  "max-backoff",
+					Value: DefaultMaxBackoff,
+					Usage: "longest wait after repeated failed polls, 0 for the default",
+				},
+				cli.StringFlag{
+					Name:  "state",
+					Value: ".bump-bedrock-watch.json",
+					Usage: "file remembering the last release seen",
+				},
+			}, bumpFlags...),
+			Action: func(c *cli.Context) {
+				if c.Duration("jitter") >= c.Duration("interval") {
+					PrintError(c.GlobalString("output"), usageErrorf("--jitter must be shorter than --interval"))
+					os.Exit(ExitFailure)
+				}
+				manifest, err := ReadManifest(c.String("manifest"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				_, cfg := openRepo(c, ".", "")
+				w := Watcher{
+					Fetch: func() (Tags, error) {
+						return GetWordPressTags(cfg.Get("tags-url"))
+					},
+					Bump: func(tags Tags) FleetResult {
+						result := RunFleet(manifest.Repos, c.Int("workers"), fleetBumper(c, tags, ""))
+						Print(c.GlobalString("output"), FleetText(result), result)
+						return result
+					},
+					StatePath:  c.String("state"),
+					Interval:   c.Duration("interval"),
+					Jitter:     c.Duration("jitter"),
+					MaxBackoff: c.Duration("max-backoff"),
+					Log:        log.New(os.Stderr, "", log.LstdFlags),
+				}
+				stop := make(chan struct{})
+				signals := make(chan os.Signal, 1)
+				signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+				go func() {
+					<-signals
+					close(stop)
+				}()
+				w.Run(stop)
+			},
+		},
+		{
+			Name:  "serve",
+			Usage: "Receive GitHub webhooks for WordPress releases and bump every repository of a manifest",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "manifest, m",
+					Value: "sites.json",
+					Usage: "JSON manifest listing the repositories",
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Value: ":8080",
+					Usage: "address to listen on",
+				},
+				cli.StringFlag{
+					Name:   "secret",
+					Usage:  "webhook secret the signatures are checked with",
+					EnvVar: "BUMP_BEDROCK_WEBHOOK_SECRET",
+				},
+				cli.StringFlag{
+					Name:  "repository",
+					Usage: "owner/name of the repository to accept events from, defaults to the one tags-url lists",
+				},
+				cli.StringFlag{
+					Name:  "queue",
+					Value: ".bump-bedrock-queue.json",
+					Usage: "file the queued bumps are kept in",
+				},
+			}, bumpFlags...),
+			Action: func(c *cli.Context) {
+				if c.String("secret") == "" {
+					PrintError(c.GlobalString("output"), errors.New("serve needs a webhook secret, pass --secret or set BUMP_BEDROCK_WEBHOOK_SECRET"))
+					os.Exit(ExitFailure)
+				}
+				manifest, err := ReadManifest(c.String("manifest"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				queue, err := OpenQueue(c.String("queue"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				_, cfg := openRepo(c, ".", "")
+				repository := c.String("repository")
+				if repository == "" {
+					repository = TagsRepository(cfg.Get("tags-url"))
+				}
+				logger := log.New(os.Stderr, "", log.LstdFlags)
+
+				stop := make(chan struct{})
+				runner := JobRunner{
+					Fetch: func() (Tags, error) {
+						return GetWordPressTags(cfg.Get("tags-url"))
+					},
+					Bumper: func(tags Tags, release string) FleetBumper {
+						return fleetBumper(c, tags, release)
+					},
+					Report: func(result FleetResult) {
+						Print(c.GlobalString("output"), FleetText(result), result)
+					},
+				}
+				go queue.Work(stop, runner.Run, logger)
+
+				http.Handle("/", WebhookHandler{
+					Secret:     c.String("secret"),
+					Repository: repository,
+					Repos:      manifest.Repos,
+					Queue:      queue,
+				})
+				logger.Printf("listening on %s", c.String("listen"))
+				if err := http.ListenAndServe(c.String("listen"), nil); err != nil {
+					close(stop)
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+			},
+		},
+		{
+			Name:  "discover",
+			Usage: "Find Bedrock projects under a directory and print them as a fleet manifest",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "include",
+					Usage: "comma separated globs a project path must match",
+				},
+				cli.StringFlag{
+					Name:  "exclude",
+					Usage: "comma separated globs of directories to skip",
+				},
+				cli.IntFlag{
+					Name:  "max-depth",
+					Usage: "how many directories deep to search, 0 for no limit",
+				},
+				cli.StringFlag{
+					Name:  "manifest, m",
+					Usage: "write the manifest to this file instead of stdout",
+				},
+			},
+			Action: func(c *cli.Context) {
+				manifest, warnings, err := Discover(defaultPath(c.Args().First()), DiscoverOptions{
+					Include:  splitList(c.String("include")),
+					Exclude:  splitList(c.String("exclude")),
+					MaxDepth: c.Int("max-depth"),
+				})
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				for _, w := range warnings {
+					fmt.Fprintln(os.Stderr, "warning: "+w)
+				}
+				if c.String("manifest") == "" {
+					Print(OutputJSON, "", manifest)
+					return
+				}
+				if err := WriteManifest(c.String("manifest"), manifest); err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				Print(c.GlobalString("output"), fmt.Sprintf("wrote %d repos to %s\n", len(manifest.Repos), c.String("manifest")), manifest)
+			},
+		},
+		{
+			Name:  "report",
+			Usage: "Report how far behind every repository of a manifest is",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "manifest, m",
+					Value: "sites.json",
+					Usage: "JSON manifest listing the repositories",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: ReportMarkdown,
+					Usage: "report format: markdown, html or csv",
+				},
+			},
+			Action: func(c *cli.Context) {
+				manifest, err := ReadManifest(c.String("manifest"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				_, cfg := openRepo(c, ".", "")
+				report := BuildReport(manifest.Repos, fetchTags(c, cfg), func(path string) (bedrock.BedrockRepoInstance, SecurityReleases, error) {
+					cfg, err := LoadConfig(path, c)
+					if err != nil {
+						return bedrock.BedrockRepoInstance{}, nil, err
+					}
+					b, err := ConfigureRepo(path, cfg, "")
+					return b, ParseSecurityReleases(cfg.Get("security-releases")), err
+				})
+				text, err := report.Render(c.String("format"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				Print(c.GlobalString("output"), text, report)
+			},
+		},
+		{
+			Name:  "versions",
+			Usage: "List available WordPress releases",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "constraint",
+					Usage: "only list releases matching a Composer constraint, e.g. \"~4.2\"",
+				},
+				cli.StringFlag{
+					Name:  "stability",
+					Value: "stable",
+					Usage: "minimum stability: " + strings.Join(Stabilities, ", "),
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "only list releases from this date (YYYY-MM-DD) on",
+				},
+				cli.StringFlag{
+					Name:  "until",
+					Usage: "only list releases up to this date (YYYY-MM-DD)",
+				},
+				cli.StringFlag{
+					Name:  "security-releases",
+					Usage: "comma separated WordPress versions that are security releases",
+				},
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "mark the release the Bedrock repository at this path is on",
+				},
+			},
+			Action: func(c *cli.Context) {
+				filter := VersionFilter{Constraint: c.String("constraint")}
+				var err error
+				if filter.MinStability, err = ParseStability(c.String("stability")); err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				for _, d := range []struct {
+					flag string
+					into *time.Time
+				}{{"since", &filter.Since}, {"until", &filter.Until}} {
+					if c.String(d.flag) == "" {
+						continue
+					}
+					if *d.into, err = time.Parse("2006-01-02", c.String(d.flag)); err != nil {
+						PrintError(c.GlobalString("output"), fmt.Errorf("--%s: %s", d.flag, err))
+						os.Exit(ExitFailure)
+					}
+				}
+				path := c.String("path")
+				b, cfg := openRepo(c, defaultPath(path), "")
+				current := ""
+				if path != "" {
+					if current, _, err = b.InstalledWordPressVersion(); err != nil {
+						PrintError(c.GlobalString("output"), err)
+						os.Exit(ExitCode(err))
+					}
+				}
+				list, err := ListVersions(fetchTags(c, cfg), filter, ParseSecurityReleases(cfg.Get("security-releases")), current)
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				Print(c.GlobalString("output"), VersionsText(list), list)
+			},
+		},
+		{
+			Name:  "check",
+			Usage: "Exit non-zero when the WordPress version at a path is outdated, without changing anything",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "security-releases",
+					Usage: "comma separated WordPress versions that are security releases",
+				},
+				cli.BoolFlag{
+					Name:  "security-only",
+					Usage: "fail when a missed release is a security release",
+				},
+				cli.IntFlag{
+					Name:  "max-releases",
+					Usage: "fail when more than this many releases behind",
+				},
+				cli.IntFlag{
+					Name:  "max-days",
+					Usage: "fail when the oldest missed release is more than this many days old",
+				},
+			},
+			Action: func(c *cli.Context) {
+				b, cfg := openRepo(c, defaultPath(c.Args().First()), "")
+				result, err := Check(b, fetchTags(c, cfg), CheckOptions{
+					Security:     ParseSecurityReleases(cfg.Get("security-releases")),
+					SecurityOnly: c.Bool("security-only"),
+					MaxReleases:  c.Int("max-releases"),
+					MaxDays:      c.Int("max-days"),
+				})
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+				} else {
+					Print(c.GlobalString("output"), CheckText(result), result)
+				}
+				os.Exit(CheckExitCode(result, err))
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "Summarise the versions and changelog of the Bedrock repository at a path",
+			Action: func(c *cli.Context) {
+				b, cfg := openRepo(c, defaultPath(c.Args().First()), "")
+				status, err := RepoStatus(b, fetchTags(c, cfg))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				Print(c.GlobalString("output"), StatusText(status), status)
+			},
+		},
+		{
+			Name:  "explain",
+			Usage: "Explain which newer releases the version policy of a repository accepts and why",
+			Action: func(c *cli.Context) {
+				b, cfg := openRepo(c, defaultPath(c.Args().First()), "")
+				explanation, err := Explain(b, fetchTags(c, cfg), NewPolicy(cfg))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				Print(c.GlobalString("output"), ExplainText(explanation), explanation)
+			},
+		},
+		{
+			Name:  "release-notes",
+			Usage: "Print the changelog section for a release (default: latest)",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "path",
+					Value: ".",
+					Usage: "path to the Bedrock repository",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "markdown",
+					Usage: "output format: markdown or json",
+				},
+			},
+			Action: func(c *cli.Context) {
+				format := c.String("format")
+				if c.GlobalString("output") == OutputJSON {
+					format = OutputJSON
+				}
+				b, _ := openRepo(c, c.String("path"), "")
+				notes, err := ReleaseNotes(b, c.Args().First(), format)
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				fmt.Print(notes)
+			},
+		},
+		{
+			Name:  "lint-changelog",
+			Usage: "Check CHANGELOG.md for malformed headings, ordering and empty sections",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "fix",
+					Usage: "rewrite malformed headings in place",
+				},
+				cli.StringFlag{
+					Name:  "date-layout",
+					Value: bedrock.DefaultDateLayout,
+					Usage: "Go time layout of the changelog heading dates",
+				},
+			},
+			Action: func(c *cli.Context) {
+				b, _ := openRepo(c, defaultPath(c.Args().First()), "")
+				problems, err := LintChangelog(b, c.Bool("fix"), c.GlobalString("output"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				if problems > 0 {
+					os.Exit(ExitFailure)
+				}
+			},
+		},
+		{
+			Name:  "init",
+			Usage: "Write a starter " + config.FileName + " detected from the repository at a path",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "overwrite an existing configuration file",
+				},
+			},
+			Action: func(c *cli.Context) {
+				result, err := InitConfig(defaultPath(c.Args().First()), c.Bool("force"))
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitCode(err))
+				}
+				Print(c.GlobalString("output"), InitText(result), result)
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Print the effective configuration of a repository and where each value came from",
 			Action: func(c *cli.Context) {
-				Bump(
-					bedrock.NewBedrock(c.Args().First()),
-					GetWordPressTags(APITagsUrl)[0].Name,
-				)
+				cfg, err := LoadConfig(defaultPath(c.Args().First()), c)
+				if err != nil {
+					PrintError(c.GlobalString("output"), err)
+					os.Exit(ExitFailure)
+				}
+				Print(c.GlobalString("output"), ConfigText(cfg), cfg.Settings())
 			},
 		},
 	}
 
-	app.Run(os.Args)
+	return app
 }
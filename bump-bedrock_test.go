@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
 	"github.com/austinpray/bump-bedrock/bedrock/mocks"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func captureStdout(f func()) string {
@@ -61,8 +68,9 @@ func TestGetVersion(t *testing.T) {
 func TestGetWordPressTags(t *testing.T) {
 	ts := mockTags()
 
-	tags := GetWordPressTags(ts.URL)
+	tags, err := GetWordPressTags(ts.URL)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, tags)
 	assert.Equal(t, "4.2.1", tags[0].Name, "first tag should be 4.2.1")
 	assert.Equal(t, 1, len(tags), "should have one array element")
@@ -71,10 +79,172 @@ func TestGetWordPressTags(t *testing.T) {
 func TestBump(t *testing.T) {
 	testBedrock := new(mocks.BedrockRepo)
 
-	testBedrock.On("UpdateWordPressVersion", "4.2.0").Return("4.2.0", nil)
+	testBedrock.On("UpdateWordPressVersion", "4.2.0").Return(bedrock.Result{Status: bedrock.StatusUpdated}, nil)
 
-	Bump(testBedrock, "4.2.0")
+	output := captureStdout(func() {
+		assert.Equal(t, bedrock.StatusUpdated, Bump(testBedrock, "4.2.0").Status)
+	})
+	assert.Equal(t, "updated successfully\n", output)
 
 	testBedrock.AssertExpectations(t)
 
 }
+
+func TestConfigureDates(t *testing.T) {
+	b, err := ConfigureDates(bedrock.NewBedrock("yee"), "2015-05-06", "UTC", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "2015-05-06", b.Today())
+
+	b, err = ConfigureDates(bedrock.NewBedrock("yee"), "2015-05-06", "America/Chicago", "02/01/2006")
+	assert.Nil(t, err)
+	assert.Equal(t, "06/05/2015", b.Today())
+
+	_, err = ConfigureDates(bedrock.NewBedrock("yee"), "", "Mars/Olympus_Mons", "")
+	assert.NotNil(t, err)
+
+	_, err = ConfigureDates(bedrock.NewBedrock("yee"), "May 6", "UTC", "")
+	assert.NotNil(t, err)
+}
+
+func TestReleaseNotes(t *testing.T) {
+	b := bedrock.NewBedrock("./bedrock/fixtures")
+
+	notes, err := ReleaseNotes(b, "", "markdown")
+	assert.Nil(t, err)
+	assert.Equal(t, "* Update to WordPress 4.2.1\n", notes)
+
+	notes, err = ReleaseNotes(b, "1.3.4", "json")
+	assert.Nil(t, err)
+	assert.Contains(t, notes, `"version": "1.3.4"`)
+	assert.Contains(t, notes, `"* WordPress 4.1.1 fix"`)
+
+	_, err = ReleaseNotes(b, "0.0.1", "markdown")
+	assert.NotNil(t, err)
+
+	_, err = ReleaseNotes(b, "", "yaml")
+	assert.NotNil(t, err)
+}
+
+func TestWriteReleaseNotes(t *testing.T) {
+	output := captureStdout(func() {
+		WriteReleaseNotes("* notes\n", "-")
+	})
+	assert.Equal(t, "* notes\n", output)
+}
+
+func TestLintChangelog(t *testing.T) {
+	var problems int
+	output := captureStdout(func() {
+		problems, _ = LintChangelog(bedrock.NewBedrock("./bedrock/fixtures"), false, OutputText)
+	})
+	assert.Equal(t, 0, problems)
+	assert.Equal(t, "", output)
+
+	output = captureStdout(func() {
+		LintChangelog(bedrock.NewBedrock("./bedrock/fixtures"), false, OutputJSON)
+	})
+	assert.Contains(t, output, `"diagnostics": []`)
+
+	_, err := LintChangelog(bedrock.NewBedrock("./nope"), false, OutputText)
+	assert.NotNil(t, err)
+}
+
+func runGit(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		panic(string(out))
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// makeGitFixture copies the bedrock fixtures into a fresh git repository
+// with a bare "origin" remote, returning both paths.
+func makeGitFixture(test string) (string, string) {
+	os.Setenv("GIT_AUTHOR_NAME", "test")
+	os.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	os.Setenv("GIT_COMMITTER_NAME", "test")
+	os.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	dir := fmt.Sprintf("/tmp/bump-bedrock-test/%s-%s-%s", strconv.FormatInt(time.Now().UnixNano(), 10), strconv.Itoa(os.Getpid()), test)
+	remote := dir + "-origin.git"
+	runGit("/tmp", "init", "-q", "--bare", remote)
+	runGit("/tmp", "init", "-q", dir)
+	runGit(dir, "checkout", "-q", "-b", "master")
+	if err := exec.Command("cp", "-rf", "./bedrock/fixtures/.", dir).Run(); err != nil {
+		panic(err)
+	}
+	runGit(dir, "add", "-A")
+	runGit(dir, "commit", "-q", "-m", "init")
+	runGit(dir, "remote", "add", "origin", remote)
+	return dir, remote
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	dir, remote := makeGitFixture("openPullRequest")
+
+	b := bedrock.NewBedrock(dir)
+	b.UpdateChangelog("4.2.2")
+	info, err := NewBumpInfo(b, "4.2.1", "4.2.2")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.3.7", info.ProjectVersion)
+	assert.Nil(t, b.RecordBump(bedrock.GitOptions{Branch: DefaultPullRequestBranch, Commit: true}, info))
+
+	var created map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprintln(w, `[]`)
+		case "POST":
+			contents, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(contents, &created)
+			fmt.Fprintln(w, `{"number": 1, "html_url": "https://github.com/roots/site/pull/1"}`)
+		}
+	}))
+	defer ts.Close()
+
+	url, err := OpenPullRequest(b, PullRequestOptions{
+		Remote: "origin",
+		Base:   "master",
+		Title:  bedrock.DefaultCommitMessage,
+		API:    ts.URL,
+		Repo:   "roots/site",
+	}, info)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/roots/site/pull/1", url)
+	assert.Equal(t, "Update to WordPress 4.2.2", created["title"])
+	assert.Equal(t, "wordpress-4.2.2", created["head"])
+	assert.Equal(t, "* Update to WordPress 4.2.2\n", created["body"])
+	assert.Equal(t,
+		runGit(dir, "rev-parse", "HEAD"),
+		runGit(remote, "rev-parse", "wordpress-4.2.2"),
+	)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitList(" a, ,b"))
+	assert.Equal(t, []string{}, splitList(""))
+}
+
+func TestGetWordPressTagsPages(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+		if r.URL.Query().Get("page") == "2" {
+			w.Header().Set("Link", `<`+ts.URL+`/tags?per_page=100&page=1>; rel="prev", <`+ts.URL+`/tags?per_page=100&page=1>; rel="first"`)
+			fmt.Fprintln(w, `[{"name": "4.1.5"}, {"name": "4.1.4"}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+ts.URL+`/tags?per_page=100&page=2>; rel="next", <`+ts.URL+`/tags?per_page=100&page=2>; rel="last"`)
+		fmt.Fprintln(w, `[{"name": "4.2.2"}, {"name": "4.2.1"}]`)
+	}))
+	defer ts.Close()
+
+	tags, err := GetWordPressTags(ts.URL + "/tags")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4.1.4", "4.1.5", "4.2.1", "4.2.2"}, tags.Versions())
+	_, ok := tags.Find("4.1.4")
+	assert.True(t, ok)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"strings"
+	"time"
+)
+
+// CheckOptions narrows when an outdated repository fails a check. With no
+// thresholds set any outdated repository fails; otherwise it fails when at
+// least one of the set thresholds is crossed.
+type CheckOptions struct {
+	Security     SecurityReleases
+	SecurityOnly bool
+	MaxReleases  int
+	MaxDays      int
+	Now          bedrock.Clock
+}
+
+// CheckResult is what the check command reports.
+type CheckResult struct {
+	Repo             string   `json:"repo"`
+	WordPressVersion string   `json:"wordpress_version"`
+	Source           string   `json:"source"`
+	LatestVersion    string   `json:"latest_version"`
+	Outdated         bool     `json:"outdated"`
+	Behind           []string `json:"behind"`
+	SecurityReleases []string `json:"security_releases"`
+	DaysBehind       int      `json:"days_behind,omitempty"`
+	Failed           bool     `json:"failed"`
+}
+
+// Check compares the WordPress version of b, preferring composer.lock over
+// the composer.json requirement, with the newest release in tags. It never
+// modifies the repository.
+func Check(b bedrock.BedrockRepoInstance, tags Tags, opts CheckOptions) (CheckResult, error) {
+	r := CheckResult{
+		Repo:             b.Path(),
+		LatestVersion:    tags.Latest(),
+		Behind:           []string{},
+		SecurityReleases: []string{},
+	}
+	current, source, err := b.InstalledWordPressVersion()
+	r.Source = source
+	if err != nil {
+		return r, err
+	}
+	r.WordPressVersion = current
+
+	r.Behind = tags.Newer(current)
+	r.Outdated = len(r.Behind) > 0
+	if !r.Outdated {
+		return r, nil
+	}
+	for _, v := range r.Behind {
+		if opts.Security[v] {
+			r.SecurityReleases = append(r.SecurityReleases, v)
+		}
+	}
+	if opts.MaxDays > 0 {
+		tag, _ := tags.Find(r.Behind[0])
+		released, err := ReleaseDate(tag)
+		if err != nil {
+			return r, err
+		}
+		now := time.Now
+		if opts.Now != nil {
+			now = opts.Now
+		}
+		r.DaysBehind = int(now().Sub(released).Hours() / 24)
+	}
+
+	if !opts.SecurityOnly && opts.MaxReleases == 0 && opts.MaxDays == 0 {
+		r.Failed = true
+	}
+	if opts.SecurityOnly && len(r.SecurityReleases) > 0 {
+		r.Failed = true
+	}
+	if opts.MaxReleases > 0 && len(r.Behind) > opts.MaxReleases {
+		r.Failed = true
+	}
+	if opts.MaxDays > 0 && r.DaysBehind > opts.MaxDays {
+		r.Failed = true
+	}
+	return r, nil
+}
+
+// CheckText renders a check result for the terminal.
+func CheckText(r CheckResult) string {
+	if !r.Outdated {
+		return fmt.Sprintf("WordPress %s (%s) is current\n", r.WordPressVersion, r.Source)
+	}
+	text := fmt.Sprintf("WordPress %s (%s) is %d release(s) behind %s\n",
+		r.WordPressVersion, r.Source, len(r.Behind), r.LatestVersion)
+	if len(r.SecurityReleases) > 0 {
+		text += fmt.Sprintf("missed security releases: %s\n", strings.Join(r.SecurityReleases, ", "))
+	}
+	if r.DaysBehind > 0 {
+		text += fmt.Sprintf("%d days since WordPress %s was released\n", r.DaysBehind, r.Behind[0])
+	}
+	return text
+}
+
+// CheckExitCode is ExitOutdated when r failed the check.
+func CheckExitCode(r CheckResult, err error) int {
+	if err != nil {
+		return ExitCode(err)
+	}
+	if r.Failed {
+		return ExitOutdated
+	}
+	return ExitUpdated
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckCurrent(t *testing.T) {
+	dir, _ := makeGitFixture("checkCurrent")
+
+	result, err := Check(bedrock.NewBedrock(dir), testTags("4.2.1", "4.2"), CheckOptions{})
+
+	assert.Nil(t, err)
+	assert.False(t, result.Outdated)
+	assert.Equal(t, "composer.json", result.Source)
+	assert.Equal(t, ExitUpdated, CheckExitCode(result, err))
+	assert.Equal(t, "WordPress 4.2.1 (composer.json) is current\n", CheckText(result))
+}
+
+func TestCheckOutdated(t *testing.T) {
+	dir, _ := makeGitFixture("checkOutdated")
+	b := bedrock.NewBedrock(dir)
+	tags := testTags("4.2.4", "4.2.3", "4.2.2", "4.2.1")
+
+	result, err := Check(b, tags, CheckOptions{Security: SecurityReleases{"4.2.3": true}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4.2.2", "4.2.3", "4.2.4"}, result.Behind)
+	assert.Equal(t, []string{"4.2.3"}, result.SecurityReleases)
+	assert.Equal(t, ExitOutdated, CheckExitCode(result, err))
+	assert.Equal(t, "WordPress 4.2.1 (composer.json) is 3 release(s) behind 4.2.4\nmissed security releases: 4.2.3\n", CheckText(result))
+
+	result, _ = Check(b, tags, CheckOptions{SecurityOnly: true})
+	assert.False(t, result.Failed)
+
+	result, _ = Check(b, tags, CheckOptions{MaxReleases: 3})
+	assert.False(t, result.Failed)
+	result, _ = Check(b, tags, CheckOptions{MaxReleases: 2})
+	assert.True(t, result.Failed)
+
+	// the latest release is the newest stable one, whatever the API order
+	result, _ = Check(b, testTags("4.3-RC1", "4.2.1", "4.2.2"), CheckOptions{})
+	assert.Equal(t, "4.2.2", result.LatestVersion)
+	assert.Equal(t, "WordPress 4.2.1 (composer.json) is 1 release(s) behind 4.2.2\n", CheckText(result))
+}
+
+func TestCheckPrefersComposerLock(t *testing.T) {
+	dir, _ := makeGitFixture("checkLock")
+	ioutil.WriteFile(dir+"/composer.lock", []byte(`{"packages": [
+  {"name": "composer/installers", "version": "v1.0.21"},
+  {"name": "johnpbloch/wordpress", "version": "4.2.4"}
+]}`), 0644)
+
+	result, err := Check(bedrock.NewBedrock(dir), testTags("4.2.4", "4.2.3"), CheckOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "composer.lock", result.Source)
+	assert.Equal(t, "4.2.4", result.WordPressVersion)
+	assert.False(t, result.Outdated)
+}
+
+func TestCheckMaxDays(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"commit": {"committer": {"date": "2015-07-23T12:00:00Z"}}}`)
+	}))
+	defer ts.Close()
+	dir, _ := makeGitFixture("checkMaxDays")
+	tags := Tags{{Name: "4.2.3"}, {Name: "4.2.2", Commit: Commit{Url: ts.URL + "/commits/4.2.2"}}}
+	now := bedrock.FixedClock(time.Date(2015, 8, 2, 12, 0, 0, 0, time.UTC))
+
+	result, err := Check(bedrock.NewBedrock(dir), tags, CheckOptions{MaxDays: 14, Now: now})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, result.DaysBehind)
+	assert.False(t, result.Failed)
+
+	result, _ = Check(bedrock.NewBedrock(dir), tags, CheckOptions{MaxDays: 7, Now: now})
+	assert.True(t, result.Failed)
+}
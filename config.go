@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/codegangsta/cli"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"github.com/austinpray/bump-bedrock/config"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadConfig reads the configuration of the repository at path and puts
+// the command's flags that were given, directly or through their
+// environment variables, on top.
+func LoadConfig(path string, c *cli.Context) (config.Config, error) {
+	cfg, err := config.Load(path, os.Getenv)
+	if err != nil {
+		return cfg, err
+	}
+	for _, f := range c.Command.Flags {
+		var name, envVar string
+		var isBool bool
+		switch f := f.(type) {
+		case cli.StringFlag:
+			name, envVar = f.Name, f.EnvVar
+		case cli.BoolFlag:
+			name, envVar, isBool = f.Name, f.EnvVar, true
+		default:
+			continue
+		}
+		names := strings.Split(name, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		if _, ok := config.Lookup(names[0]); !ok {
+			continue
+		}
+		if err := applyFlag(&cfg, c, names, envVar, isBool); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// applyFlag sets names[0] from the first of names given on the command
+// line, or else from the flag's own environment variables.
+func applyFlag(cfg *config.Config, c *cli.Context, names []string, envVar string, isBool bool) error {
+	for _, name := range names {
+		if !c.IsSet(name) {
+			continue
+		}
+		value := c.String(name)
+		if isBool {
+			value = strconv.FormatBool(c.Bool(name))
+		}
+		return cfg.Set(names[0], value, "flag --"+name)
+	}
+	for _, env := range strings.Split(envVar, ",") {
+		env = strings.TrimSpace(env)
+		if value := os.Getenv(env); env != "" && value != "" {
+			return cfg.Set(names[0], value, "env "+env)
+		}
+	}
+	return nil
+}
+
+// ConfigureRepo opens the repository at path as cfg describes. date fixes
+// the changelog date, as with bump --date.
+func ConfigureRepo(path string, cfg config.Config, date string) (bedrock.BedrockRepoInstance, error) {
+	b := bedrock.NewBedrock(path).WithChangelog(cfg.Get("changelog"))
+	b.Package = cfg.Get("package")
+	b.UpdateEntry = cfg.Get("update-entry")
+	b.DowngradeEntry = cfg.Get("downgrade-entry")
+	b.VersionSource = cfg.Get("version-source")
+	return ConfigureDates(b, date, cfg.Get("timezone"), cfg.Get("date-layout"))
+}
+
+// NewPolicy reads the version policy from cfg.
+func NewPolicy(cfg config.Config) Policy {
+	return Policy{
+		Ignore:        cfg.List("ignore"),
+		Hold:          cfg.Get("hold"),
+		MinAge:        cfg.Int("min-release-age"),
+		AllowSecurity: cfg.Bool("allow-security-releases"),
+		Security:      ParseSecurityReleases(cfg.Get("security-releases")),
+	}
+}
+
+// NewHooks reads the bump hooks from cfg.
+func NewHooks(cfg config.Config) bedrock.Hooks {
+	hooks := bedrock.Hooks{}
+	for _, stage := range bedrock.HookStages {
+		if command := cfg.Get("hook-" + stage); command != "" {
+			hooks[stage] = command
+		}
+	}
+	return hooks
+}
+
+// ConfigText renders the effective configuration with the source of every
+// value.
+func ConfigText(cfg config.Config) string {
+	text := ""
+	for _, s := range cfg.Settings() {
+		text += fmt.Sprintf("%-18s = %s (%s)\n", s.Key, strconv.Quote(s.Value), s.Source)
+	}
+	return text
+}
+
+// openRepo loads the configuration and repository a command works on,
+// exiting when either is invalid.
+func openRepo(c *cli.Context, path, date string) (bedrock.BedrockRepoInstance, config.Config) {
+	cfg, err := LoadConfig(path, c)
+	if err != nil {
+		PrintError(c.GlobalString("output"), err)
+		os.Exit(ExitFailure)
+	}
+	b, err := ConfigureRepo(path, cfg, date)
+	if err != nil {
+		PrintError(c.GlobalString("output"), err)
+		os.Exit(ExitFailure)
+	}
+	return b, cfg
+}
+
+// fetchTags fetches the releases from the configured tags URL, exiting on
+// failure.
+func fetchTags(c *cli.Context, cfg config.Config) Tags {
+	tags, err := GetWordPressTags(cfg.Get("tags-url"))
+	if err != nil {
+		PrintError(c.GlobalString("output"), err)
+		os.Exit(ExitCode(err))
+	}
+	return tags
+}
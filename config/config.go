@@ -0,0 +1,278 @@
+// Package config merges bump-bedrock settings from defaults, the user's
+// configuration file, the repository's .bump-bedrock.json and the
+// environment, remembering where each value came from.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"github.com/austinpray/bump-bedrock/forge"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileName is the repository configuration file.
+const FileName = ".bump-bedrock.json"
+
+// EnvPrefix starts the environment variable of every setting, e.g.
+// BUMP_BEDROCK_DATE_LAYOUT for date-layout.
+const EnvPrefix = "BUMP_BEDROCK_"
+
+// DefaultTagsURL lists the tags of the johnpbloch/wordpress package.
+const DefaultTagsURL = "https://api.github.com/repos/johnpbloch/wordpress/tags"
+
+// SourceDefault is the source of a setting nobody configured.
+const SourceDefault = "default"
+
+// Key describes a setting. Names match the command line flags that
+// override them.
+type Key struct {
+	Name    string
+	Default string
+	Bool    bool
+	Int     bool
+	Usage   string
+
+	// Trusted settings decide where requests, and the forge token, are
+	// sent, so a repository's own file may not set them.
+	Trusted bool
+}
+
+// Keys are the known settings in the order the config command prints them.
+var Keys = []Key{
+	{Name: "package", Default: bedrock.DefaultPackage, Usage: "Composer package that installs WordPress"},
+	{Name: "tags-url", Default: DefaultTagsURL, Trusted: true, Usage: "GitHub tags API URL listing WordPress releases"},
+	{Name: "changelog", Default: bedrock.DefaultChangelog, Usage: "changelog path, relative to the repository"},
+	{Name: "date-layout", Default: bedrock.DefaultDateLayout, Usage: "Go time layout of changelog heading dates"},
+	{Name: "timezone", Usage: "IANA time zone of changelog dates, default local"},
+	{Name: "version-source", Default: bedrock.VersionFromChangelog, Usage: "current project version source: changelog or tags"},
+	{Name: "update-entry", Default: bedrock.DefaultUpdateEntry, Usage: "changelog entry template for an update"},
+	{Name: "downgrade-entry", Default: bedrock.DefaultDowngradeEntry, Usage: "changelog entry template for a downgrade"},
+	{Name: "security-releases", Usage: "comma separated WordPress versions that are security releases"},
+	{Name: "record-skipped", Default: "false", Bool: true, Usage: "list skipped intermediate releases in the changelog"},
+	{Name: "ignore", Usage: "comma separated WordPress releases never to adopt"},
+	{Name: "hold", Usage: "Composer constraint adopted releases must satisfy, e.g. \"<4.3\""},
+	{Name: "min-release-age", Default: "0", Int: true, Usage: "days a release must have been out before it is adopted"},
+	{Name: "allow-security-releases", Default: "false", Bool: true, Usage: "adopt security releases despite hold and min-release-age"},
+	{Name: "hook-pre-check", Usage: "shell command run before a bump checks for updates"},
+	{Name: "hook-pre-write", Usage: "shell command run before a bump writes any file"},
+	{Name: "hook-post-write", Usage: "shell command run after a bump wrote its files"},
+	{Name: "hook-post-commit", Usage: "shell command run after a bump was committed"},
+	{Name: "branch", Usage: "branch name template for bumps"},
+	{Name: "commit", Default: "false", Bool: true, Usage: "commit bumps"},
+	{Name: "commit-message", Default: bedrock.DefaultCommitMessage, Usage: "commit message template"},
+	{Name: "tag", Default: "false", Bool: true, Usage: "tag bump commits"},
+	{Name: "tag-name", Default: bedrock.DefaultTagName, Usage: "tag name template"},
+	{Name: "tag-message", Default: bedrock.DefaultTagMessage, Usage: "tag message template"},
+	{Name: "remote", Default: "origin", Usage: "git remote bump branches are pushed to"},
+	{Name: "pr-base", Default: "master", Usage: "branch pull requests merge into"},
+	{Name: "pr-title", Default: bedrock.DefaultCommitMessage, Usage: "pull request title template"},
+	{Name: "pr-labels", Usage: "comma separated pull request labels"},
+	{Name: "pr-reviewers", Usage: "comma separated pull request reviewers"},
+	{Name: "forge", Default: forge.KindGitHub, Usage: "hosting service: github, gitlab or gitea"},
+	{Name: "forge-url", Trusted: true, Usage: "API base URL (GitHub) or server URL (GitLab, Gitea)"},
+	{Name: "forge-repo", Usage: "owner/name of the repository on the forge"},
+}
+
+// Lookup finds the key called name.
+func Lookup(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// EnvVar is the environment variable that sets name.
+func EnvVar(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// Setting is the effective value of a key and where it came from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Config holds one Setting per key.
+type Config struct {
+	settings map[string]Setting
+}
+
+// Defaults is the configuration before any file or variable is read.
+func Defaults() Config {
+	c := Config{settings: map[string]Setting{}}
+	for _, k := range Keys {
+		c.settings[k.Name] = Setting{Key: k.Name, Value: k.Default, Source: SourceDefault}
+	}
+	return c
+}
+
+// Get returns the value of key.
+func (c Config) Get(key string) string {
+	return c.settings[key].Value
+}
+
+// Bool returns the value of a boolean key.
+func (c Config) Bool(key string) bool {
+	b, _ := strconv.ParseBool(c.settings[key].Value)
+	return b
+}
+
+// Int returns the value of an integer key.
+func (c Config) Int(key string) int {
+	i, _ := strconv.Atoi(c.settings[key].Value)
+	return i
+}
+
+// List splits a comma separated value.
+func (c Config) List(key string) []string {
+	list := []string{}
+	for _, v := range strings.Split(c.Get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Set overrides key with value from source.
+func (c *Config) Set(key, value, source string) error {
+	k, ok := Lookup(key)
+	if !ok {
+		return fmt.Errorf("%s: unknown setting %q", source, key)
+	}
+	if k.Bool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: %s must be true or false, got %q", source, key, value)
+		}
+	}
+	if k.Int {
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s: %s must be a whole number, got %q", source, key, value)
+		}
+	}
+	c.settings[key] = Setting{Key: key, Value: value, Source: source}
+	return nil
+}
+
+// Settings lists every setting in Keys order.
+func (c Config) Settings() []Setting {
+	settings := []Setting{}
+	for _, k := range Keys {
+		settings = append(settings, c.settings[k.Name])
+	}
+	return settings
+}
+
+// LoadFile applies the JSON object in path. A missing file is not an
+// error. Values may be strings, booleans, numbers or lists of strings.
+func (c *Config) LoadFile(path string) error {
+	return c.loadFile(path, false)
+}
+
+// LoadRepoFile is LoadFile for a repository's own file, which may not set
+// trusted settings.
+func (c *Config) LoadRepoFile(path string) error {
+	return c.loadFile(path, true)
+}
+
+func (c *Config) loadFile(path string, repo bool) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := stringValue(values[key])
+		if err != nil {
+			return fmt.Errorf("%s: %s: %s", path, key, err)
+		}
+		if k, _ := Lookup(key); repo && k.Trusted {
+			return fmt.Errorf("%s: %s may only be set in the user configuration, the environment or a flag", path, key)
+		}
+		if err := c.Set(key, value, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := []string{}
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("lists may only contain strings")
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// LoadEnv applies the BUMP_BEDROCK_* variables set in getenv.
+func (c *Config) LoadEnv(getenv func(string) string) error {
+	for _, k := range Keys {
+		if value := getenv(EnvVar(k.Name)); value != "" {
+			if err := c.Set(k.Name, value, "env "+EnvVar(k.Name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UserPath is the user level configuration file: $BUMP_BEDROCK_CONFIG, or
+// bump-bedrock/config.json under $XDG_CONFIG_HOME or ~/.config.
+func UserPath(getenv func(string) string) string {
+	if path := getenv(EnvPrefix + "CONFIG"); path != "" {
+		return path
+	}
+	dir := getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "bump-bedrock", "config.json")
+}
+
+// Load merges, from lowest to highest precedence, the defaults, the user
+// configuration file, the repository's .bump-bedrock.json and the
+// environment. Command line flags are applied on top by the caller. The
+// repository's file may not set trusted settings.
+func Load(repo string, getenv func(string) string) (Config, error) {
+	c := Defaults()
+	if err := c.LoadFile(UserPath(getenv)); err != nil {
+		return c, err
+	}
+	if err := c.LoadRepoFile(filepath.Join(repo, FileName)); err != nil {
+		return c, err
+	}
+	return c, c.LoadEnv(getenv)
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tmpDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "bump-bedrock-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := tmpDir(t)
+	defer os.RemoveAll(dir)
+	user := filepath.Join(dir, "user.json")
+	ioutil.WriteFile(user, []byte(`{"remote": "upstream", "changelog": "HISTORY.md", "commit": true}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, FileName), []byte(`{"changelog": "CHANGES.md", "pr-labels": ["wordpress", "deps"]}`), 0644)
+
+	c, err := Load(dir, env(map[string]string{
+		"BUMP_BEDROCK_CONFIG":    user,
+		"BUMP_BEDROCK_CHANGELOG": "NEWS.md",
+	}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Setting{Key: "changelog", Value: "NEWS.md", Source: "env BUMP_BEDROCK_CHANGELOG"}, c.settings["changelog"])
+	assert.Equal(t, Setting{Key: "remote", Value: "upstream", Source: user}, c.settings["remote"])
+	assert.Equal(t, []string{"wordpress", "deps"}, c.List("pr-labels"))
+	assert.True(t, c.Bool("commit"))
+	assert.Equal(t, Setting{Key: "forge", Value: "github", Source: SourceDefault}, c.settings["forge"])
+	assert.Equal(t, len(Keys), len(c.Settings()))
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := tmpDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, FileName)
+	c := Defaults()
+
+	ioutil.WriteFile(path, []byte(`{"chnagelog": "CHANGES.md"}`), 0644)
+	assert.Equal(t, path+`: unknown setting "chnagelog"`, c.LoadFile(path).Error())
+
+	ioutil.WriteFile(path, []byte(`{"commit": "yes"}`), 0644)
+	assert.Equal(t, path+`: commit must be true or false, got "yes"`, c.LoadFile(path).Error())
+
+	assert.Nil(t, c.LoadFile(filepath.Join(dir, "missing.json")))
+}
+
+func TestUserPath(t *testing.T) {
+	assert.Equal(t, "/home/me/.config/bump-bedrock/config.json", UserPath(env(map[string]string{"HOME": "/home/me"})))
+	assert.Equal(t, "/xdg/bump-bedrock/config.json", UserPath(env(map[string]string{"HOME": "/home/me", "XDG_CONFIG_HOME": "/xdg"})))
+}
+
+func TestLoadRepoFileTrusted(t *testing.T) {
+	dir := tmpDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, FileName)
+	c := Defaults()
+
+	for _, key := range []string{"tags-url", "forge-url"} {
+		ioutil.WriteFile(path, []byte(`{"`+key+`": "https://evil.example.com"}`), 0644)
+		assert.Equal(t, path+`: `+key+` may only be set in the user configuration, the environment or a flag`, c.LoadRepoFile(path).Error())
+		assert.Nil(t, c.LoadFile(path))
+	}
+
+	_, err := Load(dir, env(map[string]string{"HOME": dir}))
+	assert.NotNil(t, err)
+}
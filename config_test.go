@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/config"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigureRepo(t *testing.T) {
+	dir, _ := makeGitFixture("configureRepo")
+	fakeComposer(dir + "-bin")
+	os.Rename(dir+"/CHANGELOG.md", dir+"/CHANGES.md")
+	ioutil.WriteFile(dir+"/"+config.FileName, []byte(`{
+  "changelog": "CHANGES.md",
+  "update-entry": "Bump WordPress from {{.OldWordPressVersion}} to {{.WordPressVersion}}",
+  "timezone": "UTC"
+}`), 0644)
+	cfg, err := config.Load(dir, func(string) string { return "" })
+	assert.Nil(t, err)
+
+	b, err := ConfigureRepo(dir, cfg, "2015-05-01")
+	assert.Nil(t, err)
+	assert.Equal(t, dir+"/CHANGES.md", b.ChangelogPath())
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{ReleaseNotes: "-"})
+	assert.Nil(t, err)
+	assert.Equal(t, "* Bump WordPress from 4.2.1 to 4.2.2\n", result.ReleaseNotes)
+	changes, _ := ioutil.ReadFile(dir + "/CHANGES.md")
+	assert.True(t, strings.HasPrefix(string(changes), "### 1.3.7: 2015-05-01\n"))
+}
+
+func TestConfigText(t *testing.T) {
+	text := ConfigText(config.Defaults())
+	assert.Contains(t, text, "changelog          = \"CHANGELOG.md\" (default)\n")
+}
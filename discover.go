@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BedrockLayout are paths, any of which marks a directory with a
+// WordPress composer.json as a Bedrock project.
+var BedrockLayout = []string{"config/application.php", "web/wp", "web/app"}
+
+// skipDirs are never searched for projects.
+var skipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// DiscoverOptions limits the search of Discover. Globs match the path
+// relative to the root, or its last element. MaxDepth 0 searches the whole
+// tree.
+type DiscoverOptions struct {
+	Include  []string
+	Exclude  []string
+	MaxDepth int
+}
+
+// IsBedrockProject reports whether dir has a composer.json requiring a
+// WordPress core package and the Bedrock directory layout.
+func IsBedrockProject(dir string) bool {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "composer.json"))
+	if err != nil {
+		return false
+	}
+	var composer struct {
+		Require map[string]string `json:"require"`
+	}
+	if json.Unmarshal(data, &composer) != nil {
+		return false
+	}
+	wordpress := false
+	for _, pkg := range WordPressPackages {
+		if _, ok := composer.Require[pkg]; ok {
+			wordpress = true
+		}
+	}
+	if !wordpress {
+		return false
+	}
+	for _, p := range BedrockLayout {
+		if _, err := os.Stat(filepath.Join(dir, p)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover walks root for Bedrock projects and returns them as a fleet
+// manifest with absolute paths. Projects are not searched for nested ones.
+// Entries below root that cannot be read are skipped and returned as
+// warnings.
+func Discover(root string, opts DiscoverOptions) (Manifest, []string, error) {
+	m := Manifest{Repos: []ManifestRepo{}}
+	warnings := []string{}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return m, warnings, err
+	}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			warnings = append(warnings, "skipped "+path+": "+err.Error())
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." {
+			if skipDirs[info.Name()] || matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			if opts.MaxDepth > 0 && strings.Count(rel, string(filepath.Separator))+1 > opts.MaxDepth {
+				return filepath.SkipDir
+			}
+		}
+		if !IsBedrockProject(path) {
+			return nil
+		}
+		if len(opts.Include) == 0 || matchAny(opts.Include, rel) {
+			m.Repos = append(m.Repos, ManifestRepo{Name: rel, Path: path})
+		}
+		return filepath.SkipDir
+	})
+	return m, warnings, err
+}
+
+// matchAny reports whether rel, or its last element, matches one of globs.
+func matchAny(globs []string, rel string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeProject(t *testing.T, dir, pkg string, layout ...string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "composer.json"), []byte(`{"require": {"`+pkg+`": "4.2.1"}}`), 0644)
+	for _, p := range layout {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, p), []byte{}, 0644)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root, _ := ioutil.TempDir("", "bump-bedrock-discover")
+	defer os.RemoveAll(root)
+	makeProject(t, root+"/clients/acme", "johnpbloch/wordpress", "config/application.php")
+	makeProject(t, root+"/clients/acme/web/app/plugins/x", "johnpbloch/wordpress", "web/wp/index.php")
+	makeProject(t, root+"/clients/globex/site", "roots/wordpress", "web/wp/index.php")
+	makeProject(t, root+"/laravel", "laravel/framework", "config/application.php")
+	makeProject(t, root+"/plain-wp", "johnpbloch/wordpress")
+	makeProject(t, root+"/archive/old", "johnpbloch/wordpress", "config/application.php")
+	makeProject(t, root+"/vendor/some/site", "johnpbloch/wordpress", "config/application.php")
+
+	m, warnings, err := Discover(root, DiscoverOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, []ManifestRepo{
+		{Name: "archive/old", Path: root + "/archive/old"},
+		{Name: "clients/acme", Path: root + "/clients/acme"},
+		{Name: "clients/globex/site", Path: root + "/clients/globex/site"},
+	}, m.Repos)
+
+	m, _, _ = Discover(root, DiscoverOptions{Exclude: []string{"archive"}, MaxDepth: 2})
+	assert.Equal(t, []ManifestRepo{{Name: "clients/acme", Path: root + "/clients/acme"}}, m.Repos)
+
+	m, _, _ = Discover(root, DiscoverOptions{Include: []string{"clients/*/*"}})
+	assert.Equal(t, []ManifestRepo{{Name: "clients/globex/site", Path: root + "/clients/globex/site"}}, m.Repos)
+}
+
+func TestDiscoverSkipsUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read every directory")
+	}
+	root, _ := ioutil.TempDir("", "bump-bedrock-discover")
+	defer os.RemoveAll(root)
+	makeProject(t, root+"/site", "johnpbloch/wordpress", "config/application.php")
+	makeProject(t, root+"/locked/site", "johnpbloch/wordpress", "config/application.php")
+	os.Chmod(root+"/locked", 0)
+	defer os.Chmod(root+"/locked", 0755)
+
+	m, warnings, err := Discover(root, DiscoverOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []ManifestRepo{{Name: "site", Path: root + "/site"}}, m.Repos)
+	assert.Equal(t, 1, len(warnings))
+	assert.Contains(t, warnings[0], root+"/locked")
+
+	_, _, err = Discover(root+"/missing", DiscoverOptions{})
+	assert.NotNil(t, err)
+}
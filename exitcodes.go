@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+)
+
+// Exit codes, documented in README.md. 2 is left out because Go exits
+// with it when a goroutine panics.
+const (
+	ExitUpdated    = 0 // bump updated the repository, or a command succeeded
+	ExitFailure    = 1 // bad usage or a failed check such as lint-changelog
+	ExitOutdated   = 3 // check found the repository outdated
+	ExitNetwork    = 4 // fetching releases or talking to a forge failed
+	ExitRepository = 5 // reading or writing the repository failed
+	ExitComposer   = 6 // composer failed
+	ExitHook       = 7 // a hook command failed
+	ExitCurrent    = 8 // bump found nothing to update
+)
+
+// NetworkError wraps failures talking to a remote service.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// UsageError is a mistake in the arguments or flags a command was given.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// usageErrorf formats a *UsageError.
+func usageErrorf(format string, a ...interface{}) error {
+	return &UsageError{fmt.Errorf(format, a...)}
+}
+
+// ExitCode maps an error, or the error it wraps, to its exit code. Errors
+// of no known kind are repository errors.
+func ExitCode(err error) int {
+	var network *NetworkError
+	var usage *UsageError
+	var composer *bedrock.ComposerError
+	var hook *bedrock.HookError
+	switch {
+	case err == nil:
+		return ExitUpdated
+	case errors.As(err, &usage):
+		return ExitFailure
+	case errors.As(err, &network):
+		return ExitNetwork
+	case errors.As(err, &composer):
+		return ExitComposer
+	case errors.As(err, &hook):
+		return ExitHook
+	}
+	return ExitRepository
+}
+
+// BumpExitCode is the exit code of a bump with result r.
+func BumpExitCode(r bedrock.Result, err error) int {
+	if err != nil {
+		return ExitCode(err)
+	}
+	if r.Status == bedrock.StatusCurrent {
+		return ExitCurrent
+	}
+	return ExitUpdated
+}
+
+// exitOnPanic turns a panic from the bedrock package into a repository
+// (or Composer) error exit instead of a stack trace.
+func exitOnPanic(exit func(int)) {
+	if r := recover(); r != nil {
+		err, ok := r.(error)
+		if !ok {
+			err = fmt.Errorf("%v", r)
+		}
+		fmt.Println(err)
+		exit(ExitCode(err))
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitUpdated, ExitCode(nil))
+	assert.Equal(t, ExitNetwork, ExitCode(&NetworkError{errors.New("timeout")}))
+	assert.Equal(t, ExitComposer, ExitCode(&bedrock.ComposerError{Err: errors.New("exit status 1")}))
+	assert.Equal(t, ExitRepository, ExitCode(errors.New("CHANGELOG.md: no such file")))
+	assert.Equal(t, ExitFailure, ExitCode(usageErrorf("unknown release notes format %q", "pdf")))
+	assert.Equal(t, ExitHook, ExitCode(&bedrock.HookError{Stage: bedrock.HookPreWrite, Err: errors.New("exit status 1")}))
+
+	// wrapped errors keep their code
+	assert.Equal(t, ExitNetwork, ExitCode(fmt.Errorf("opening pull request: %w", &NetworkError{errors.New("timeout")})))
+	assert.Equal(t, ExitComposer, ExitCode(fmt.Errorf("repo a: %w", &bedrock.ComposerError{Err: errors.New("exit status 1")})))
+}
+
+func TestRunAppUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"bump", "--bogus-flag"},
+		{"bogus-command"},
+		{"check", "--max-days", "notanumber"},
+		{"fleet", "bogus-command"},
+	} {
+		code := -1
+		captureStdout(func() {
+			app := newApp()
+			app.Writer = ioutil.Discard
+			code = runApp(app, append([]string{"bump-bedrock"}, args...))
+		})
+		assert.Equal(t, ExitFailure, code, "%v", args)
+	}
+
+	output := captureStdout(func() {
+		code := runApp(newApp(), []string{"bump-bedrock", "help"})
+		assert.Equal(t, ExitUpdated, code)
+	})
+	assert.Contains(t, output, "bump that bedrock version son")
+}
+
+func TestReleaseNotesUsageError(t *testing.T) {
+	b := bedrock.NewBedrock("./bedrock/fixtures")
+	_, err := ReleaseNotes(b, "9.9.9", "markdown")
+	assert.Equal(t, ExitFailure, ExitCode(err))
+	_, err = ReleaseNotes(b, "", "pdf")
+	assert.Equal(t, ExitFailure, ExitCode(err))
+}
+
+func TestPushFailureIsNetworkError(t *testing.T) {
+	dir, _ := makeGitFixture("pushFails")
+	b := bedrock.NewBedrock(dir)
+
+	_, err := OpenPullRequest(b, PullRequestOptions{Remote: "nowhere"}, bedrock.BumpInfo{})
+
+	assert.Equal(t, ExitNetwork, ExitCode(err))
+}
+
+func TestBumpExitCode(t *testing.T) {
+	assert.Equal(t, ExitUpdated, BumpExitCode(bedrock.Result{Status: bedrock.StatusUpdated}, nil))
+	assert.Equal(t, ExitCurrent, BumpExitCode(bedrock.Result{Status: bedrock.StatusCurrent}, nil))
+	assert.Equal(t, ExitNetwork, BumpExitCode(bedrock.Result{Status: bedrock.StatusFailed}, &NetworkError{errors.New("timeout")}))
+}
+
+func TestGetWordPressTagsNetworkError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	_, err := GetWordPressTags(ts.URL)
+
+	assert.Equal(t, ExitNetwork, ExitCode(err))
+	assert.Contains(t, err.Error(), "403 Forbidden")
+}
+
+func TestRunBumpComposerExitCode(t *testing.T) {
+	dir, _ := makeGitFixture("composer-fails")
+	bin := dir + "-failing-bin"
+	os.MkdirAll(bin, 0755)
+	ioutil.WriteFile(bin+"/composer.phar", []byte("#!/bin/sh\necho 'could not resolve' >&2\nexit 2\n"), 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+":"+path)
+	defer os.Setenv("PATH", path)
+
+	result, err := RunBump(bedrock.NewBedrock(dir), testTags("4.2.2"), BumpOptions{})
+
+	assert.Equal(t, ExitComposer, BumpExitCode(result, err))
+	assert.Contains(t, err.Error(), "could not resolve")
+}
+
+func TestExitOnPanic(t *testing.T) {
+	code := -1
+	output := captureStdout(func() {
+		func() {
+			defer exitOnPanic(func(c int) { code = c })
+			panic(fmt.Errorf("open composer.json: no such file"))
+		}()
+	})
+	assert.Equal(t, ExitRepository, code)
+	assert.Equal(t, "open composer.json: no such file\n", output)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+)
+
+// Manifest lists the repositories of a fleet.
+type Manifest struct {
+	Repos []ManifestRepo `json:"repos"`
+}
+
+// ManifestRepo is one repository of a fleet. Relative paths are resolved
+// against the manifest's directory.
+type ManifestRepo struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+// ReadManifest reads a fleet manifest.
+func ReadManifest(path string) (Manifest, error) {
+	m := Manifest{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %s", path, err)
+	}
+	for i, repo := range m.Repos {
+		if repo.Path == "" {
+			return m, fmt.Errorf("%s: repo %d has no path", path, i+1)
+		}
+		if !filepath.IsAbs(repo.Path) {
+			m.Repos[i].Path = filepath.Join(filepath.Dir(path), repo.Path)
+		}
+		if repo.Name == "" {
+			m.Repos[i].Name = repo.Path
+		}
+	}
+	return m, nil
+}
+
+// WriteManifest writes m as indented JSON.
+func WriteManifest(path string, m Manifest) error {
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(out, '\n'), 0644)
+}
+
+// FleetRepo is the outcome of bumping one repository of a fleet.
+type FleetRepo struct {
+	Name string `json:"name"`
+	bedrock.Result
+	err error
+}
+
+// FleetResult is the outcome of bumping every repository of a fleet.
+type FleetResult struct {
+	Repos   []FleetRepo `json:"repos"`
+	Updated int         `json:"updated"`
+	Current int         `json:"current"`
+	Failed  int         `json:"failed"`
+}
+
+// FleetBumper bumps a single repository of a fleet.
+type FleetBumper func(repo ManifestRepo) (bedrock.Result, error)
+
+// RunFleet bumps every repository with at most workers at a time. A
+// failure, or a panic, in one repository does not affect the others.
+// Results are in manifest order.
+func RunFleet(repos []ManifestRepo, workers int, bump FleetBumper) FleetResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]FleetRepo, len(repos))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = bumpFleetRepo(repos[i], bump)
+			}
+		}()
+	}
+	for i := range repos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	r := FleetResult{Repos: results}
+	for _, repo := range results {
+		switch repo.Status {
+		case bedrock.StatusUpdated:
+			r.Updated++
+		case bedrock.StatusCurrent:
+			r.Current++
+		default:
+			r.Failed++
+		}
+	}
+	return r
+}
+
+func bumpFleetRepo(repo ManifestRepo, bump FleetBumper) (r FleetRepo) {
+	r.Name = repo.Name
+	defer func() {
+		if p := recover(); p != nil {
+			err, ok := p.(error)
+			if !ok {
+				err = fmt.Errorf("%v", p)
+			}
+			r.Result = bedrock.NewBedrock(repo.Path).NewResult()
+			r.Fail(err)
+			r.err = err
+		}
+	}()
+	r.Result, r.err = bump(repo)
+	if r.err != nil && r.Status != bedrock.StatusFailed {
+		r.Fail(r.err)
+	}
+	return r
+}
+
+// FleetText renders a summary table of a fleet bump.
+func FleetText(r FleetResult) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tSTATUS\tDETAILS")
+	for _, repo := range r.Repos {
+		details := repo.NewWordPressVersion
+		switch {
+		case repo.Status == bedrock.StatusFailed && len(repo.Errors) > 0:
+			details = repo.Errors[0]
+		case repo.Status == bedrock.StatusUpdated:
+			details = repo.OldWordPressVersion + " -> " + repo.NewWordPressVersion
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", repo.Name, repo.Status, details)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "%d repos: %d updated, %d current, %d failed\n", len(r.Repos), r.Updated, r.Current, r.Failed)
+	return buf.String()
+}
+
+// FleetExitCode is the exit code of the first failed repository, or
+// ExitUpdated when none failed.
+func FleetExitCode(r FleetResult) int {
+	for _, repo := range r.Repos {
+		if repo.Status == bedrock.StatusFailed {
+			if repo.err == nil {
+				return ExitRepository
+			}
+			return ExitCode(repo.err)
+		}
+	}
+	return ExitUpdated
+}
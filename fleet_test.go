@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-manifest")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/sites.json", []byte(`{"repos": [{"path": "a"}, {"name": "b", "path": "/srv/b"}]}`), 0644)
+
+	m, err := ReadManifest(dir + "/sites.json")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []ManifestRepo{{Name: "a", Path: dir + "/a"}, {Name: "b", Path: "/srv/b"}}, m.Repos)
+}
+
+func TestRunFleet(t *testing.T) {
+	repos := []ManifestRepo{{Name: "a", Path: "a"}, {Name: "b", Path: "b"}, {Name: "c", Path: "c"}, {Name: "d", Path: "d"}}
+	var running, most int32
+
+	result := RunFleet(repos, 2, func(repo ManifestRepo) (bedrock.Result, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		if n > atomic.LoadInt32(&most) {
+			atomic.StoreInt32(&most, n)
+		}
+		r := bedrock.Result{Repo: repo.Path, Status: bedrock.StatusCurrent, NewWordPressVersion: "4.2.2"}
+		switch repo.Name {
+		case "a":
+			r.Status, r.OldWordPressVersion = bedrock.StatusUpdated, "4.2.1"
+		case "b":
+			return r, &bedrock.ComposerError{Args: []string{"composer.phar"}, Err: errors.New("exit status 1"), Output: "boom"}
+		case "c":
+			panic(errors.New("open c/composer.json: no such file"))
+		}
+		return r, nil
+	})
+
+	assert.True(t, most <= 2)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Current)
+	assert.Equal(t, 2, result.Failed)
+	assert.Equal(t, []string{"open c/composer.json: no such file"}, result.Repos[2].Errors)
+	assert.Equal(t, ExitComposer, FleetExitCode(result))
+	assert.Equal(t, `REPO  STATUS   DETAILS
+a     updated  4.2.1 -> 4.2.2
+b     failed   composer.phar: exit status 1: boom
+c     failed   open c/composer.json: no such file
+d     current  4.2.2
+4 repos: 1 updated, 1 current, 2 failed
+`, FleetText(result))
+}
+
+func TestRunFleetBumps(t *testing.T) {
+	a, _ := makeGitFixture("fleetA")
+	b, _ := makeGitFixture("fleetB")
+	fakeComposer(a + "-bin")
+
+	result := RunFleet([]ManifestRepo{{Name: "a", Path: a}, {Name: "b", Path: b}}, 2, func(repo ManifestRepo) (bedrock.Result, error) {
+		return RunBump(bedrock.NewBedrock(repo.Path), testTags("4.2.2"), BumpOptions{Git: bedrock.GitOptions{Commit: true}})
+	})
+
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, ExitUpdated, FleetExitCode(result))
+	assert.Equal(t, "Update to WordPress 4.2.2", runGit(b, "log", "-1", "--format=%s"))
+}
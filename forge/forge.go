@@ -0,0 +1,111 @@
+// Package forge opens change requests (pull or merge requests) for a bump
+// on the hosting service of a repository.
+package forge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Supported forge kinds.
+const (
+	KindGitHub = "github"
+	KindGitLab = "gitlab"
+	KindGitea  = "gitea"
+)
+
+// Forge opens or updates the change request for a bump branch and returns
+// its web URL.
+type Forge interface {
+	OpenChangeRequest(cr ChangeRequest) (string, error)
+}
+
+// ChangeRequest is the pull request opened for a bump. Head is the pushed
+// bump branch and Base the branch it should merge into.
+type ChangeRequest struct {
+	Title     string
+	Body      string
+	Head      string
+	Base      string
+	Labels    []string
+	Reviewers []string
+}
+
+// New returns the Forge of the given kind for repo ("owner/name"). An empty
+// baseURL selects the public service where there is one.
+func New(kind, baseURL, token, repo string) (Forge, error) {
+	switch kind {
+	case "", KindGitHub:
+		return GitHub{BaseURL: baseURL, Token: token, Repo: repo}, nil
+	case KindGitLab:
+		return GitLab{BaseURL: baseURL, Token: token, Repo: repo}, nil
+	case KindGitea:
+		if baseURL == "" {
+			return nil, fmt.Errorf("gitea needs the URL of the server")
+		}
+		return Gitea{BaseURL: baseURL, Token: token, Repo: repo}, nil
+	}
+	return nil, fmt.Errorf("unknown forge %q", kind)
+}
+
+// apiClient makes JSON requests against a forge REST API.
+type apiClient struct {
+	name    string
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (c apiClient) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(c.baseURL, "/")+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	client := c.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	contents, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s %s: %s: %s", c.name, method, path, res.Status, strings.TrimSpace(string(contents)))
+	}
+	if out != nil {
+		return json.Unmarshal(contents, out)
+	}
+	return nil
+}
+
+var remoteRepo = regexp.MustCompile(`^(?:[a-z+]+://[^/]+/|[^@]+@[^:]+:)(.+?)(?:\.git)?/?$`)
+
+// RepoFromRemote extracts "owner/name" from an ssh or https remote URL.
+func RepoFromRemote(remote string) (string, error) {
+	m := remoteRepo.FindStringSubmatch(strings.TrimSpace(remote))
+	if m == nil || !strings.Contains(m[1], "/") {
+		return "", fmt.Errorf("cannot determine repository from remote %q", remote)
+	}
+	return m[1], nil
+}
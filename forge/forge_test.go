@@ -0,0 +1,79 @@
+package forge
+
+import (
+	"encoding/json"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordedRequest struct {
+	Method, Path string
+	Header       http.Header
+	Body         map[string]interface{}
+}
+
+// fixtureServer replays recorded API responses. routes maps
+// "METHOD /request/uri" to a file under ./fixtures.
+func fixtureServer(routes map[string]string, requests *[]recordedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		contents, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(contents, &body)
+		*requests = append(*requests, recordedRequest{r.Method, r.URL.RequestURI(), r.Header, body})
+
+		fixture, ok := routes[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		recorded, err := ioutil.ReadFile("./fixtures/" + fixture)
+		if err != nil {
+			panic(err)
+		}
+		w.Write(recorded)
+	}))
+}
+
+var testChangeRequest = ChangeRequest{
+	Title:     "Update to WordPress 4.2.2",
+	Body:      "* Update to WordPress 4.2.2\n",
+	Head:      "wordpress-4.2.2",
+	Base:      "master",
+	Labels:    []string{"wordpress"},
+	Reviewers: []string{"swalkinshaw"},
+}
+
+func TestNew(t *testing.T) {
+	f, err := New("", "", "secret", "roots/site")
+	assert.Nil(t, err)
+	assert.Equal(t, GitHub{Token: "secret", Repo: "roots/site"}, f)
+
+	f, err = New(KindGitLab, "https://gitlab.example.com", "", "roots/site")
+	assert.Nil(t, err)
+	assert.IsType(t, GitLab{}, f)
+
+	_, err = New(KindGitea, "", "", "roots/site")
+	assert.NotNil(t, err)
+
+	_, err = New("bitbucket", "", "", "roots/site")
+	assert.NotNil(t, err)
+}
+
+func TestRepoFromRemote(t *testing.T) {
+	for _, remote := range []string{
+		"git@github.com:roots/site.git",
+		"https://github.com/roots/site.git",
+		"https://github.com/roots/site",
+		"ssh://git@github.com/roots/site.git",
+	} {
+		repo, err := RepoFromRemote(remote)
+		assert.Nil(t, err, remote)
+		assert.Equal(t, "roots/site", repo, remote)
+	}
+
+	_, err := RepoFromRemote("/srv/git/site.git")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,123 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Gitea creates pull requests through the Gitea v1 API. BaseURL is the
+// server root, e.g. https://git.example.com, and Repo is "owner/name".
+type Gitea struct {
+	BaseURL string
+	Token   string
+	Repo    string
+	Client  *http.Client
+}
+
+type giteaPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
+
+type giteaLabel struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (g Gitea) api() apiClient {
+	c := apiClient{
+		name:    "gitea",
+		baseURL: strings.TrimRight(g.BaseURL, "/") + "/api/v1",
+		headers: map[string]string{},
+		client:  g.Client,
+	}
+	if g.Token != "" {
+		c.headers["Authorization"] = "token " + g.Token
+	}
+	return c
+}
+
+// labelIDs maps label names to the IDs Gitea wants.
+func (g Gitea) labelIDs(api apiClient, names []string) ([]int, error) {
+	labels := []giteaLabel{}
+	if err := api.do("GET", "/repos/"+g.Repo+"/labels", nil, &labels); err != nil {
+		return nil, err
+	}
+	byName := map[string]int{}
+	for _, l := range labels {
+		byName[l.Name] = l.ID
+	}
+	ids := []int{}
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("gitea: unknown label %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// OpenChangeRequest creates a pull request for cr, or updates the open one
+// for the same head branch, then applies labels and reviewers.
+func (g Gitea) OpenChangeRequest(cr ChangeRequest) (string, error) {
+	api := g.api()
+	repo := "/repos/" + g.Repo
+
+	open := []giteaPull{}
+	if err := api.do("GET", repo+"/pulls?state=open", nil, &open); err != nil {
+		return "", err
+	}
+
+	var pull giteaPull
+	var err error
+	existing := -1
+	for i, p := range open {
+		if p.Head.Ref == cr.Head {
+			existing = i
+			break
+		}
+	}
+	if existing >= 0 {
+		err = api.do("PATCH", fmt.Sprintf("%s/pulls/%d", repo, open[existing].Number), map[string]string{
+			"title": cr.Title,
+			"body":  cr.Body,
+		}, &pull)
+	} else {
+		err = api.do("POST", repo+"/pulls", map[string]string{
+			"title": cr.Title,
+			"body":  cr.Body,
+			"head":  cr.Head,
+			"base":  cr.Base,
+		}, &pull)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(cr.Labels) > 0 {
+		ids, err := g.labelIDs(api, cr.Labels)
+		if err != nil {
+			return "", err
+		}
+		err = api.do("POST", fmt.Sprintf("%s/issues/%d/labels", repo, pull.Number), map[string][]int{
+			"labels": ids,
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(cr.Reviewers) > 0 {
+		err := api.do("POST", fmt.Sprintf("%s/pulls/%d/requested_reviewers", repo, pull.Number), map[string][]string{
+			"reviewers": cr.Reviewers,
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	return pull.HTMLURL, nil
+}
@@ -0,0 +1,62 @@
+package forge
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGiteaCreatePullRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v1/repos/roots/site/pulls?state=open":             "gitea/pulls-empty.json",
+		"POST /api/v1/repos/roots/site/pulls":                       "gitea/pull.json",
+		"GET /api/v1/repos/roots/site/labels":                       "gitea/labels.json",
+		"POST /api/v1/repos/roots/site/issues/5/labels":             "gitea/labels-added.json",
+		"POST /api/v1/repos/roots/site/pulls/5/requested_reviewers": "gitea/requested_reviewers.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := Gitea{BaseURL: ts.URL, Token: "secret", Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(testChangeRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://git.example.com/roots/site/pulls/5", url)
+	assert.Equal(t, 5, len(requests))
+	assert.Equal(t, "token secret", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "wordpress-4.2.2", requests[1].Body["head"])
+	assert.Equal(t, []interface{}{float64(3)}, requests[3].Body["labels"])
+	assert.Equal(t, []interface{}{"swalkinshaw"}, requests[4].Body["reviewers"])
+}
+
+func TestGiteaUpdatePullRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v1/repos/roots/site/pulls?state=open": "gitea/pulls-open.json",
+		"PATCH /api/v1/repos/roots/site/pulls/5":        "gitea/pull.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := Gitea{BaseURL: ts.URL, Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(ChangeRequest{Title: "Update to WordPress 4.2.2", Head: "wordpress-4.2.2", Base: "master"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://git.example.com/roots/site/pulls/5", url)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "PATCH", requests[1].Method)
+}
+
+func TestGiteaUnknownLabel(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v1/repos/roots/site/pulls?state=open": "gitea/pulls-empty.json",
+		"POST /api/v1/repos/roots/site/pulls":           "gitea/pull.json",
+		"GET /api/v1/repos/roots/site/labels":           "gitea/labels.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := Gitea{BaseURL: ts.URL, Repo: "roots/site"}
+	_, err := g.OpenChangeRequest(ChangeRequest{Head: "wordpress-4.2.2", Labels: []string{"security"}})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "security")
+}
@@ -0,0 +1,95 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitHubAPI is the public GitHub REST API.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// GitHub creates pull requests through the GitHub REST API. Repo is
+// "owner/name".
+type GitHub struct {
+	BaseURL string
+	Token   string
+	Repo    string
+	Client  *http.Client
+}
+
+type githubPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g GitHub) api() apiClient {
+	c := apiClient{
+		name:    "github",
+		baseURL: g.BaseURL,
+		headers: map[string]string{"Accept": "application/vnd.github.v3+json"},
+		client:  g.Client,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultGitHubAPI
+	}
+	if g.Token != "" {
+		c.headers["Authorization"] = "token " + g.Token
+	}
+	return c
+}
+
+// OpenChangeRequest creates a pull request for cr, or updates the title
+// and body of an open one for the same head branch, then applies labels and
+// reviewers.
+func (g GitHub) OpenChangeRequest(cr ChangeRequest) (string, error) {
+	api := g.api()
+	owner := strings.Split(g.Repo, "/")[0]
+	repo := "/repos/" + g.Repo
+
+	existing := []githubPull{}
+	query := url.Values{"state": {"open"}, "head": {owner + ":" + cr.Head}}
+	if err := api.do("GET", repo+"/pulls?"+query.Encode(), nil, &existing); err != nil {
+		return "", err
+	}
+
+	var pull githubPull
+	if len(existing) > 0 {
+		err := api.do("PATCH", fmt.Sprintf("%s/pulls/%d", repo, existing[0].Number), map[string]string{
+			"title": cr.Title,
+			"body":  cr.Body,
+		}, &pull)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err := api.do("POST", repo+"/pulls", map[string]string{
+			"title": cr.Title,
+			"body":  cr.Body,
+			"head":  cr.Head,
+			"base":  cr.Base,
+		}, &pull)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(cr.Labels) > 0 {
+		err := api.do("POST", fmt.Sprintf("%s/issues/%d/labels", repo, pull.Number), map[string][]string{
+			"labels": cr.Labels,
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(cr.Reviewers) > 0 {
+		err := api.do("POST", fmt.Sprintf("%s/pulls/%d/requested_reviewers", repo, pull.Number), map[string][]string{
+			"reviewers": cr.Reviewers,
+		}, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	return pull.HTMLURL, nil
+}
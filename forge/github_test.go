@@ -0,0 +1,86 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeGitHub stands in for the GitHub API. open holds the pull requests
+// returned when listing.
+func fakeGitHub(open string, requests *[]recordedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		contents, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(contents, &body)
+		*requests = append(*requests, recordedRequest{r.Method, r.URL.RequestURI(), r.Header, body})
+
+		switch {
+		case r.Header.Get("Authorization") != "token secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"message": "Bad credentials"}`)
+		case r.Method == "GET":
+			fmt.Fprintln(w, open)
+		case r.Method == "POST" && r.URL.Path == "/repos/roots/site/pulls":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"number": 7, "html_url": "https://github.com/roots/site/pull/7"}`)
+		case r.Method == "PATCH":
+			fmt.Fprintln(w, `{"number": 3, "html_url": "https://github.com/roots/site/pull/3"}`)
+		default:
+			fmt.Fprintln(w, `{}`)
+		}
+	}))
+}
+
+func TestGitHubCreatePullRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fakeGitHub(`[]`, &requests)
+	defer ts.Close()
+
+	g := GitHub{BaseURL: ts.URL, Token: "secret", Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(testChangeRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/roots/site/pull/7", url)
+	assert.Equal(t, 4, len(requests))
+	assert.Equal(t, "/repos/roots/site/pulls?head=roots%3Awordpress-4.2.2&state=open", requests[0].Path)
+	assert.Equal(t, "POST", requests[1].Method)
+	assert.Equal(t, "wordpress-4.2.2", requests[1].Body["head"])
+	assert.Equal(t, "master", requests[1].Body["base"])
+	assert.Equal(t, "* Update to WordPress 4.2.2\n", requests[1].Body["body"])
+	assert.Equal(t, "/repos/roots/site/issues/7/labels", requests[2].Path)
+	assert.Equal(t, []interface{}{"wordpress"}, requests[2].Body["labels"])
+	assert.Equal(t, "/repos/roots/site/pulls/7/requested_reviewers", requests[3].Path)
+	assert.Equal(t, []interface{}{"swalkinshaw"}, requests[3].Body["reviewers"])
+}
+
+func TestGitHubUpdatePullRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fakeGitHub(`[{"number": 3, "html_url": "https://github.com/roots/site/pull/3"}]`, &requests)
+	defer ts.Close()
+
+	g := GitHub{BaseURL: ts.URL, Token: "secret", Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(ChangeRequest{Title: "t", Body: "b", Head: "h", Base: "master"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/roots/site/pull/3", url)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "PATCH", requests[1].Method)
+	assert.Equal(t, "/repos/roots/site/pulls/3", requests[1].Path)
+}
+
+func TestGitHubError(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fakeGitHub(`[]`, &requests)
+	defer ts.Close()
+
+	g := GitHub{BaseURL: ts.URL, Token: "wrong", Repo: "roots/site"}
+	_, err := g.OpenChangeRequest(testChangeRequest)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Bad credentials")
+}
@@ -0,0 +1,103 @@
+package forge
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitLabURL is the public GitLab instance.
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLab creates merge requests through the GitLab v4 API. BaseURL is the
+// server root, e.g. https://gitlab.example.com, and Repo the project path.
+type GitLab struct {
+	BaseURL string
+	Token   string
+	Repo    string
+	Client  *http.Client
+}
+
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+type gitlabUser struct {
+	ID int `json:"id"`
+}
+
+func (g GitLab) api() apiClient {
+	base := g.BaseURL
+	if base == "" {
+		base = DefaultGitLabURL
+	}
+	c := apiClient{
+		name:    "gitlab",
+		baseURL: strings.TrimRight(base, "/") + "/api/v4",
+		headers: map[string]string{},
+		client:  g.Client,
+	}
+	if g.Token != "" {
+		c.headers["PRIVATE-TOKEN"] = g.Token
+	}
+	return c
+}
+
+// reviewerIDs looks up the user IDs GitLab wants for reviewers.
+func (g GitLab) reviewerIDs(api apiClient, usernames []string) ([]int, error) {
+	ids := []int{}
+	for _, username := range usernames {
+		users := []gitlabUser{}
+		if err := api.do("GET", "/users?"+url.Values{"username": {username}}.Encode(), nil, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("gitlab: unknown reviewer %q", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+// OpenChangeRequest creates a merge request for cr, or updates the open one
+// for the same source branch.
+func (g GitLab) OpenChangeRequest(cr ChangeRequest) (string, error) {
+	api := g.api()
+	project := "/projects/" + url.QueryEscape(g.Repo)
+
+	reviewers, err := g.reviewerIDs(api, cr.Reviewers)
+	if err != nil {
+		return "", err
+	}
+	fields := map[string]interface{}{
+		"title":       cr.Title,
+		"description": cr.Body,
+	}
+	if len(cr.Labels) > 0 {
+		fields["labels"] = strings.Join(cr.Labels, ",")
+	}
+	if len(reviewers) > 0 {
+		fields["reviewer_ids"] = reviewers
+	}
+
+	existing := []gitlabMergeRequest{}
+	query := url.Values{"state": {"opened"}, "source_branch": {cr.Head}}
+	if err := api.do("GET", project+"/merge_requests?"+query.Encode(), nil, &existing); err != nil {
+		return "", err
+	}
+
+	var mr gitlabMergeRequest
+	if len(existing) > 0 {
+		err = api.do("PUT", fmt.Sprintf("%s/merge_requests/%d", project, existing[0].IID), fields, &mr)
+	} else {
+		fields["source_branch"] = cr.Head
+		fields["target_branch"] = cr.Base
+		err = api.do("POST", project+"/merge_requests", fields, &mr)
+	}
+	if err != nil {
+		return "", err
+	}
+	return mr.WebURL, nil
+}
@@ -0,0 +1,62 @@
+package forge
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGitLabCreateMergeRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v4/users?username=swalkinshaw":                                                      "gitlab/users.json",
+		"GET /api/v4/projects/roots%2Fsite/merge_requests?source_branch=wordpress-4.2.2&state=opened": "gitlab/merge_requests-empty.json",
+		"POST /api/v4/projects/roots%2Fsite/merge_requests":                                           "gitlab/merge_request.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := GitLab{BaseURL: ts.URL, Token: "secret", Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(testChangeRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://gitlab.example.com/roots/site/-/merge_requests/12", url)
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, "secret", requests[0].Header.Get("PRIVATE-TOKEN"))
+
+	created := requests[2].Body
+	assert.Equal(t, "wordpress-4.2.2", created["source_branch"])
+	assert.Equal(t, "master", created["target_branch"])
+	assert.Equal(t, "* Update to WordPress 4.2.2\n", created["description"])
+	assert.Equal(t, "wordpress", created["labels"])
+	assert.Equal(t, []interface{}{float64(42)}, created["reviewer_ids"])
+}
+
+func TestGitLabUpdateMergeRequest(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v4/projects/roots%2Fsite/merge_requests?source_branch=wordpress-4.2.2&state=opened": "gitlab/merge_requests-open.json",
+		"PUT /api/v4/projects/roots%2Fsite/merge_requests/12":                                         "gitlab/merge_request.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := GitLab{BaseURL: ts.URL, Repo: "roots/site"}
+	url, err := g.OpenChangeRequest(ChangeRequest{Title: "Update to WordPress 4.2.2", Head: "wordpress-4.2.2", Base: "master"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://gitlab.example.com/roots/site/-/merge_requests/12", url)
+	assert.Equal(t, "Update to WordPress 4.2.2", requests[1].Body["title"])
+	assert.Nil(t, requests[1].Body["source_branch"])
+}
+
+func TestGitLabUnknownReviewer(t *testing.T) {
+	requests := []recordedRequest{}
+	ts := fixtureServer(map[string]string{
+		"GET /api/v4/users?username=nobody": "gitlab/merge_requests-empty.json",
+	}, &requests)
+	defer ts.Close()
+
+	g := GitLab{BaseURL: ts.URL, Repo: "roots/site"}
+	_, err := g.OpenChangeRequest(ChangeRequest{Reviewers: []string{"nobody"}})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "nobody")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"github.com/austinpray/bump-bedrock/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WordPressPackages are the Composer packages init recognises as
+// WordPress, most common first.
+var WordPressPackages = []string{
+	"johnpbloch/wordpress",
+	"roots/wordpress",
+	"johnpbloch/wordpress-core",
+	"roots/wordpress-no-content",
+}
+
+// ChangelogNames are the changelog files init looks for, in order.
+var ChangelogNames = []string{"CHANGELOG.md", "CHANGES.md", "HISTORY.md", "changelog.md"}
+
+// InitResult describes the configuration init detected and wrote.
+type InitResult struct {
+	Path         string            `json:"path"`
+	Settings     map[string]string `json:"settings"`
+	HeadingStyle string            `json:"heading_style"`
+	Warnings     []string          `json:"warnings"`
+}
+
+// DetectConfig inspects composer.json, the changelog and the git tags of
+// the repository at path for the settings of a starter configuration.
+func DetectConfig(path string) (InitResult, error) {
+	r := InitResult{
+		Path:         filepath.Join(path, config.FileName),
+		Settings:     map[string]string{},
+		HeadingStyle: bedrock.StyleNone,
+		Warnings:     []string{},
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(path, "composer.json"))
+	if err != nil {
+		return r, err
+	}
+	var composer struct {
+		Require map[string]string `json:"require"`
+	}
+	if err := json.Unmarshal(data, &composer); err != nil {
+		return r, fmt.Errorf("composer.json: %s", err)
+	}
+	for _, pkg := range WordPressPackages {
+		if _, ok := composer.Require[pkg]; ok {
+			r.Settings["package"] = pkg
+			break
+		}
+	}
+	if r.Settings["package"] == "" {
+		return r, fmt.Errorf("composer.json does not require any of %s", strings.Join(WordPressPackages, ", "))
+	}
+	if r.Settings["package"] != bedrock.DefaultPackage {
+		// A repository's own file may not set tags-url, so point the user
+		// at the places that can.
+		r.Warnings = append(r.Warnings, fmt.Sprintf("tags-url defaults to the %s releases, set it for %s in the user configuration or %s",
+			bedrock.DefaultPackage, r.Settings["package"], config.EnvVar("tags-url")))
+	}
+
+	b := bedrock.NewBedrock(path)
+	for _, name := range ChangelogNames {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			r.Settings["changelog"] = name
+			b = b.WithChangelog(name)
+			break
+		}
+	}
+	r.Settings["version-source"] = bedrock.VersionFromChangelog
+	if r.Settings["changelog"] == "" {
+		r.Settings["changelog"] = bedrock.DefaultChangelog
+		r.Warnings = append(r.Warnings, "no changelog found, bump will need "+bedrock.DefaultChangelog)
+	} else {
+		changelog, err := b.ReadChangelog()
+		if err != nil {
+			return r, err
+		}
+		format := changelog.Format()
+		r.HeadingStyle = format.Style
+		if format.DateLayout != "" {
+			r.Settings["date-layout"] = format.DateLayout
+		}
+		if format.Style == bedrock.StyleLoose {
+			r.Warnings = append(r.Warnings, "changelog headings are not \"### x.y.z: date\", run lint-changelog --fix")
+		}
+		if _, ok := changelog.Release(""); !ok {
+			if tags, err := b.GitTags(); err == nil && bedrock.LatestSemverTag(tags) != "" {
+				r.Settings["version-source"] = bedrock.VersionFromTags
+			}
+		}
+	}
+	return r, nil
+}
+
+// InitConfig writes the detected configuration to .bump-bedrock.json,
+// refusing to replace an existing file unless force is set.
+func InitConfig(path string, force bool) (InitResult, error) {
+	r, err := DetectConfig(path)
+	if err != nil {
+		return r, err
+	}
+	if _, err := os.Stat(r.Path); err == nil && !force {
+		return r, usageErrorf("%s already exists, pass --force to overwrite it", r.Path)
+	}
+	out, err := json.MarshalIndent(r.Settings, "", "  ")
+	if err != nil {
+		return r, err
+	}
+	return r, ioutil.WriteFile(r.Path, append(out, '\n'), 0644)
+}
+
+// InitText renders an init result for the terminal.
+func InitText(r InitResult) string {
+	keys := []string{}
+	for key := range r.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	text := "wrote " + r.Path + "\n"
+	for _, key := range keys {
+		text += fmt.Sprintf("  %s: %s\n", key, r.Settings[key])
+	}
+	text += "changelog heading style: " + r.HeadingStyle + "\n"
+	for _, w := range r.Warnings {
+		text += "warning: " + w + "\n"
+	}
+	return text
+}
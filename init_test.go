@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/config"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInitConfig(t *testing.T) {
+	dir, _ := makeGitFixture("init")
+
+	result, err := InitConfig(dir, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"package":        "johnpbloch/wordpress",
+		"changelog":      "CHANGELOG.md",
+		"date-layout":    "2006-01-02",
+		"version-source": "changelog",
+	}, result.Settings)
+	assert.Equal(t, "bedrock", result.HeadingStyle)
+	assert.Contains(t, InitText(result), "\nchangelog heading style: bedrock\n")
+	written, _ := ioutil.ReadFile(dir + "/" + config.FileName)
+	assert.Equal(t, `{
+  "changelog": "CHANGELOG.md",
+  "date-layout": "2006-01-02",
+  "package": "johnpbloch/wordpress",
+  "version-source": "changelog"
+}
+`, string(written))
+
+	_, err = InitConfig(dir, false)
+	assert.Equal(t, dir+"/"+config.FileName+" already exists, pass --force to overwrite it", err.Error())
+	_, err = InitConfig(dir, true)
+	assert.Nil(t, err)
+}
+
+func TestDetectConfigTags(t *testing.T) {
+	dir, _ := makeGitFixture("initTags")
+	os.Rename(dir+"/CHANGELOG.md", dir+"/HISTORY.md")
+	ioutil.WriteFile(dir+"/HISTORY.md", []byte("## v1.0.0 - 2015-04-27\n"), 0644)
+	runGit(dir, "tag", "v1.0.0")
+
+	result, err := DetectConfig(dir)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "HISTORY.md", result.Settings["changelog"])
+	assert.Equal(t, "loose", result.HeadingStyle)
+	assert.Equal(t, "tags", result.Settings["version-source"])
+	assert.Equal(t, 1, len(result.Warnings))
+}
+
+func TestDetectConfigTagsURL(t *testing.T) {
+	dir, _ := makeGitFixture("initRoots")
+	composer, _ := ioutil.ReadFile(dir + "/composer.json")
+	ioutil.WriteFile(dir+"/composer.json", []byte(strings.Replace(string(composer), "johnpbloch/wordpress", "roots/wordpress", 1)), 0644)
+
+	result, err := DetectConfig(dir)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "roots/wordpress", result.Settings["package"])
+	assert.Equal(t, []string{"tags-url defaults to the johnpbloch/wordpress releases, set it for roots/wordpress in the user configuration or BUMP_BEDROCK_TAGS_URL"}, result.Warnings)
+	_, ok := result.Settings["tags-url"]
+	assert.False(t, ok)
+}
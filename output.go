@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"os"
+	"strings"
+)
+
+// Output formats selected with the global --output flag.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Print writes text, or v as indented JSON when format is OutputJSON.
+func Print(format, text string, v interface{}) error {
+	if format != OutputJSON {
+		_, err := fmt.Print(text)
+		return err
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(out))
+	return err
+}
+
+// PrintBump prints a bump result. Release notes written to stdout are
+// printed alone there, with the status on stderr, so they can be piped.
+func PrintBump(format string, r bedrock.Result) error {
+	if format == OutputJSON || r.ReleaseNotes == "" {
+		return Print(format, BumpText(r), r)
+	}
+	if _, err := fmt.Fprint(os.Stderr, BumpText(r)); err != nil {
+		return err
+	}
+	_, err := fmt.Print(r.ReleaseNotes)
+	return err
+}
+
+// BumpText renders the status of a bump result the way the bump command
+// prints it, without the release notes.
+func BumpText(r bedrock.Result) string {
+	var out []string
+	if r.DryRun {
+		if r.Status != bedrock.StatusUpdated {
+			return r.Message() + "\n"
+		}
+		out = append(out, r.Diff+"Commands that would run:")
+		for _, action := range r.Actions {
+			out = append(out, "  "+action)
+		}
+		return strings.Join(out, "\n") + "\n"
+	}
+
+	for _, action := range r.Actions {
+		if strings.HasPrefix(action, "rolled back ") {
+			out = append(out, action)
+		}
+	}
+	if r.Status != bedrock.StatusFailed {
+		out = append(out, r.Message())
+	}
+	if r.ChangeRequestURL != "" {
+		out = append(out, r.ChangeRequestURL)
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// PrintError reports err as text, or as {"errors": [...]} in JSON.
+func PrintError(format string, err error) {
+	Print(format, err.Error()+"\n", map[string][]string{"errors": {err.Error()}})
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"path/filepath"
+	"strings"
+)
+
+// BumpOptions are the follow-up steps of a bump, set from the bump
+// command's flags.
+type BumpOptions struct {
+	To                 string
+	Release            string
+	AllowDowngrade     bool
+	Policy             Policy
+	Hooks              bedrock.Hooks
+	Git                bedrock.GitOptions
+	PullRequest        *PullRequestOptions
+	RecordSkipped      bool
+	Security           SecurityReleases
+	ReleaseNotes       string
+	ReleaseNotesFormat string
+	DryRun             bool
+}
+
+// wantsGit reports whether the bump is recorded in git.
+func (opts BumpOptions) wantsGit() bool {
+	return opts.Git.Branch != "" || opts.Git.Commit || opts.Git.Tag
+}
+
+// RunBump updates b to the newest release in tags opts.Policy accepts, to
+// opts.Release if the policy accepts it, or to opts.To, and then branches, commits, tags, opens a pull request and
+// writes release notes as opts asks, running opts.Hooks along the way.
+// Failures are recorded in the result as well as returned. A failed pre
+// hook stops the bump; a failed post hook rolls it back.
+func RunBump(b bedrock.BedrockRepoInstance, tags Tags, opts BumpOptions) (bedrock.Result, error) {
+	r := b.NewResult()
+	fail := func(err error) (bedrock.Result, error) {
+		r.Fail(err)
+		return r, err
+	}
+
+	if opts.PullRequest != nil {
+		opts.Git.Commit = true
+		if opts.Git.Branch == "" {
+			opts.Git.Branch = DefaultPullRequestBranch
+		}
+	}
+	if !opts.DryRun {
+		if _, err := runHook(b, opts.Hooks, bedrock.HookPreCheck, &r, nil); err != nil {
+			return fail(err)
+		}
+	}
+	if opts.wantsGit() {
+		if err := b.CheckClean(); err != nil {
+			return fail(err)
+		}
+	}
+
+	oldWordPressVersion, err := b.ReadWordPressVersion()
+	if err != nil {
+		return fail(err)
+	}
+	opts.Policy.Security = opts.Security
+	var target string
+	if opts.Release != "" {
+		target, err = ReleaseTarget(tags, opts.Release, oldWordPressVersion, opts.Policy)
+	} else {
+		target, err = TargetVersion(tags, opts.To, oldWordPressVersion, opts.Policy)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if version.Compare(target, oldWordPressVersion, "<") && !opts.AllowDowngrade {
+		return fail(usageErrorf("WordPress %s is older than %s, pass --allow-downgrade to downgrade", target, oldWordPressVersion))
+	}
+	b.AllowDowngrade = opts.AllowDowngrade
+	if opts.RecordSkipped {
+		b.Skipped = SkippedReleases(tags, opts.Security, oldWordPressVersion, target)
+	}
+
+	if opts.DryRun {
+		return DryRun(b, target, opts)
+	}
+
+	// everything up to the commit, hooks included, is undone if a step
+	// fails
+	tx := bedrock.NewTransaction()
+	if b.NeedsUpdate(oldWordPressVersion, target) {
+		r.OldWordPressVersion, r.NewWordPressVersion = oldWordPressVersion, target
+		if _, err := runHook(b, opts.Hooks, bedrock.HookPreWrite, &r, tx); err != nil {
+			b.Rollback(tx, &r)
+			return fail(err)
+		}
+	}
+	applied, err := b.ApplyWordPressUpdate(target, tx)
+	applied.Actions = append(r.Actions, applied.Actions...)
+	r = applied
+	if err != nil {
+		b.Rollback(tx, &r)
+		return fail(err)
+	}
+	if r.Status != bedrock.StatusUpdated {
+		return r, nil
+	}
+	hooked, err := runHook(b, opts.Hooks, bedrock.HookPostWrite, &r, tx)
+	if err != nil {
+		b.Rollback(tx, &r)
+		return fail(err)
+	}
+	changed := map[string]bool{}
+	for _, f := range r.FilesChanged {
+		changed[f] = true
+	}
+	for _, f := range hooked {
+		if !changed[f] {
+			r.FilesChanged = append(r.FilesChanged, f)
+			opts.Git.Files = append(opts.Git.Files, f)
+		}
+	}
+	info := bedrock.BumpInfo{
+		OldWordPressVersion: r.OldWordPressVersion,
+		WordPressVersion:    r.NewWordPressVersion,
+		ProjectVersion:      r.NewProjectVersion,
+	}
+	actions, err := opts.Git.Commands(info, r.FilesChanged)
+	if err != nil {
+		b.Rollback(tx, &r)
+		return fail(err)
+	}
+	from := ""
+	if opts.Git.Branch != "" {
+		if from, err = b.CurrentBranch(); err != nil {
+			b.Rollback(tx, &r)
+			return fail(err)
+		}
+	}
+	if err := b.CommitBump(opts.Git, info); err != nil {
+		b.Rollback(tx, &r)
+		return fail(err)
+	}
+	// undo drops what CommitBump and TagBump did and rolls back the files
+	undo := func(git bedrock.GitOptions, err error) (bedrock.Result, error) {
+		if undoErr := b.UndoBump(git, info, from); undoErr != nil {
+			r.Errors = append(r.Errors, "undoing commit failed: "+undoErr.Error())
+		} else if git.Commit {
+			r.Actions = append(r.Actions, "undid commit")
+		}
+		b.Rollback(tx, &r)
+		return fail(err)
+	}
+	if err := b.TagBump(opts.Git, info); err != nil {
+		git := opts.Git
+		git.Tag = false
+		return undo(git, err)
+	}
+	for _, action := range actions {
+		r.Actions = append(r.Actions, "ran "+strings.Join(action, " "))
+	}
+	if opts.Git.Commit {
+		if _, err := runHook(b, opts.Hooks, bedrock.HookPostCommit, &r, tx); err != nil {
+			return undo(opts.Git, err)
+		}
+	}
+
+	if opts.PullRequest != nil {
+		url, err := OpenPullRequest(b, *opts.PullRequest, info)
+		if err != nil {
+			return fail(err)
+		}
+		r.ChangeRequestURL = url
+		r.Actions = append(r.Actions, "opened change request "+url)
+	}
+	if opts.ReleaseNotes != "" {
+		notes, err := ReleaseNotes(b, "", opts.ReleaseNotesFormat)
+		if err != nil {
+			return fail(err)
+		}
+		if opts.ReleaseNotes == "-" {
+			r.ReleaseNotes = notes
+		} else if err := WriteReleaseNotes(notes, opts.ReleaseNotes); err != nil {
+			return fail(err)
+		} else {
+			r.Actions = append(r.Actions, "wrote release notes to "+opts.ReleaseNotes)
+		}
+	}
+	return r, nil
+}
+
+// DryRun computes the diff of every file a bump would change and the
+// commands it would run, without touching the repository.
+func DryRun(b bedrock.BedrockRepoInstance, newWordPressVersion string, opts BumpOptions) (bedrock.Result, error) {
+	r := b.NewResult()
+	r.DryRun = true
+	current, err := b.ReadWordPressVersion()
+	if err != nil {
+		r.Fail(err)
+		return r, err
+	}
+	r.OldWordPressVersion, r.NewWordPressVersion = current, current
+
+	plan, err := b.PlanWordPressUpdate(newWordPressVersion)
+	if err != nil {
+		r.Fail(err)
+		return r, err
+	}
+	if len(plan.Changes) == 0 {
+		return r, nil
+	}
+	r.Status = bedrock.StatusUpdated
+	r.NewWordPressVersion = newWordPressVersion
+
+	for _, change := range plan.Changes {
+		rel, _ := filepath.Rel(b.Path(), change.Path)
+		r.FilesChanged = append(r.FilesChanged, rel)
+	}
+	// the project versions come from the same source a real bump uses
+	changelog, err := b.ReadChangelog()
+	if err == nil {
+		r.OldProjectVersion, err = b.CurrentVersion(changelog.Lines)
+	}
+	if err != nil {
+		r.Fail(err)
+		return r, err
+	}
+	r.NewProjectVersion = bedrock.NextVersion(r.OldProjectVersion)
+	r.Diff = plan.Diff(b.Path())
+
+	info := bedrock.BumpInfo{
+		OldWordPressVersion: r.OldWordPressVersion,
+		WordPressVersion:    r.NewWordPressVersion,
+		ProjectVersion:      r.NewProjectVersion,
+	}
+	git, err := opts.Git.Commands(info, r.FilesChanged)
+	if err != nil {
+		r.Fail(err)
+		return r, err
+	}
+	commands := append(hookCommands(opts.Hooks, bedrock.HookPreCheck, bedrock.HookPreWrite), plan.Commands...)
+	commands = append(commands, hookCommands(opts.Hooks, bedrock.HookPostWrite)...)
+	commands = append(commands, git...)
+	if opts.Git.Commit {
+		commands = append(commands, hookCommands(opts.Hooks, bedrock.HookPostCommit)...)
+	}
+	plan.Commands = commands
+	if opts.PullRequest != nil {
+		branch, err := bedrock.RenderTemplate(opts.Git.Branch, info)
+		if err != nil {
+			r.Fail(err)
+			return r, err
+		}
+		plan.Commands = append(plan.Commands,
+			[]string{"git", "push", "--force-with-lease", "--set-upstream", opts.PullRequest.Remote, branch},
+			[]string{"open", defaultForge(opts.PullRequest.Forge), "change request", branch, "->", opts.PullRequest.Base},
+		)
+	}
+	for _, cmd := range plan.Commands {
+		r.Actions = append(r.Actions, strings.Join(cmd, " "))
+	}
+	return r, nil
+}
+
+// runHook runs the hook for stage, recording it in r when it ran, and
+// returns the files it changed. With tx, rolling tx back undoes the hook.
+func runHook(b bedrock.BedrockRepoInstance, hooks bedrock.Hooks, stage string, r *bedrock.Result, tx *bedrock.Transaction) ([]string, error) {
+	var ran bool
+	var changed []string
+	var err error
+	if tx == nil {
+		ran, err = b.RunHook(hooks, stage, *r)
+	} else {
+		ran, changed, err = b.RunHookTx(hooks, stage, *r, tx)
+	}
+	if ran {
+		r.Actions = append(r.Actions, "ran "+stage+" hook")
+	}
+	return changed, err
+}
+
+// hookCommands lists the hooks configured for stages the way a dry run
+// shows them.
+func hookCommands(hooks bedrock.Hooks, stages ...string) [][]string {
+	commands := [][]string{}
+	for _, stage := range stages {
+		if hooks[stage] != "" {
+			commands = append(commands, []string{"run", stage, "hook:", hooks[stage]})
+		}
+	}
+	return commands
+}
+
+func defaultForge(kind string) string {
+	if kind == "" {
+		return "github"
+	}
+	return kind
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRunBumpDryRun(t *testing.T) {
+	dir, _ := makeGitFixture("dryRun")
+	b := bedrock.NewBedrock(dir)
+	b.Now = bedrock.FixedClock(time.Date(2015, 8, 4, 0, 0, 0, 0, time.UTC))
+	before, _ := ioutil.ReadFile(b.ChangelogPath())
+
+	result, err := RunBump(b, testTags("4.2.4", "4.2.3", "4.2.2"), BumpOptions{
+		RecordSkipped: true,
+		Security:      SecurityReleases{"4.2.2": true},
+		PullRequest:   &PullRequestOptions{Remote: "origin", Base: "master"},
+		DryRun:        true,
+	})
+	output := BumpText(result)
+
+	assert.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, "1.3.6", result.OldProjectVersion)
+	assert.Equal(t, "1.3.7", result.NewProjectVersion)
+	assert.Equal(t, []string{"composer.json", "CHANGELOG.md"}, result.FilesChanged)
+	assert.Contains(t, output, "Commands that would run:\n  composer.phar require johnpbloch/wordpress 4.2.4 --no-update --no-progress\n")
+	assert.Contains(t, output, "+* Update to WordPress 4.2.4\n+  * Includes WordPress 4.2.2 (security release)\n+  * Includes WordPress 4.2.3\n")
+	assert.Contains(t, output, "  git checkout -b wordpress-4.2.4\n")
+	assert.Contains(t, output, "  git add -- composer.json CHANGELOG.md\n")
+	assert.Contains(t, output, "  git push --force-with-lease --set-upstream origin wordpress-4.2.4\n")
+	assert.Contains(t, output, "  open github change request wordpress-4.2.4 -> master\n")
+
+	after, _ := ioutil.ReadFile(b.ChangelogPath())
+	assert.Equal(t, string(before), string(after))
+	assert.Equal(t, "master", runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"))
+}
+
+func TestRunBumpDryRunVersionSource(t *testing.T) {
+	dir, _ := makeGitFixture("dryRunTags")
+	runGit(dir, "tag", "1.4.0")
+	b := bedrock.NewBedrock(dir)
+	b.VersionSource = bedrock.VersionFromTags
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{DryRun: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "1.4.0", result.OldProjectVersion)
+	assert.Equal(t, "1.4.1", result.NewProjectVersion)
+	assert.Contains(t, result.Diff, "+### 1.4.1: ")
+}
+
+func TestRunBumpDryRunHooks(t *testing.T) {
+	dir, _ := makeGitFixture("dryRunHooks")
+	log := dir + "-hooks.log"
+	hooks := bedrock.Hooks{}
+	for _, stage := range bedrock.HookStages {
+		hooks[stage] = "echo " + stage + " >> " + log
+	}
+
+	result, err := RunBump(bedrock.NewBedrock(dir), testTags("4.2.2"), BumpOptions{
+		Hooks:  hooks,
+		Git:    bedrock.GitOptions{Commit: true},
+		DryRun: true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"run pre-check hook: echo pre-check >> " + log,
+		"run pre-write hook: echo pre-write >> " + log,
+		"composer.phar require johnpbloch/wordpress 4.2.2 --no-update --no-progress",
+		"run post-write hook: echo post-write >> " + log,
+		"git add -- composer.json CHANGELOG.md",
+		"git commit -m Update to WordPress 4.2.2",
+		"run post-commit hook: echo post-commit >> " + log,
+	}, result.Actions)
+	_, err = os.Stat(log)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunBumpDryRunCurrent(t *testing.T) {
+	result, _ := RunBump(bedrock.NewBedrock("./bedrock/fixtures"), testTags("4.2.1"), BumpOptions{DryRun: true})
+	assert.Equal(t, "nothing to update\n", BumpText(result))
+}
+
+// fakeComposer puts a composer.phar in dir and on PATH that rewrites the
+// required version in composer.json.
+func fakeComposer(dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(err)
+	}
+	script := "#!/bin/sh\nsed -i \"s#\\\"$2\\\": \\\"[^\\\"]*\\\"#\\\"$2\\\": \\\"$3\\\"#\" composer.json\n"
+	if err := ioutil.WriteFile(dir+"/composer.phar", []byte(script), 0755); err != nil {
+		panic(err)
+	}
+	os.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+}
+
+func TestRunBumpRollsBackFailedCommit(t *testing.T) {
+	dir, _ := makeGitFixture("rollback")
+	fakeComposer(dir + "-bin")
+	runGit(dir, "branch", "wordpress-4.2.2")
+	b := bedrock.NewBedrock(dir)
+	before, _ := ioutil.ReadFile(b.ChangelogPath())
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Git: bedrock.GitOptions{Branch: DefaultPullRequestBranch, Commit: true},
+	})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	assert.Equal(t, bedrock.StatusFailed, result.Status)
+	assert.Equal(t, []string{err.Error()}, result.Errors)
+	assert.Empty(t, result.FilesChanged)
+	assert.Equal(t, "rolled back CHANGELOG.md\nrolled back composer.json\n", BumpText(result))
+	after, _ := ioutil.ReadFile(b.ChangelogPath())
+	assert.Equal(t, string(before), string(after))
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+}
+
+func TestRunBumpCommits(t *testing.T) {
+	dir, _ := makeGitFixture("commit")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Git:          bedrock.GitOptions{Commit: true, Tag: true},
+		ReleaseNotes: "-",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "updated successfully\n", BumpText(result))
+	assert.Equal(t, "* Update to WordPress 4.2.2\n", result.ReleaseNotes)
+	assert.Equal(t, bedrock.Result{
+		Repo:                dir,
+		Status:              bedrock.StatusUpdated,
+		OldWordPressVersion: "4.2.1",
+		NewWordPressVersion: "4.2.2",
+		OldProjectVersion:   "1.3.6",
+		NewProjectVersion:   "1.3.7",
+		FilesChanged:        []string{"composer.json", "CHANGELOG.md"},
+		Actions: []string{
+			"ran composer.phar require johnpbloch/wordpress 4.2.2 --no-update --no-progress",
+			"updated CHANGELOG.md",
+			"ran git add -- composer.json CHANGELOG.md",
+			"ran git commit -m Update to WordPress 4.2.2",
+			"ran git tag -a 1.3.7 -m 1.3.7: Update to WordPress 4.2.2",
+		},
+		ReleaseNotes: "* Update to WordPress 4.2.2\n",
+		Errors:       []string{},
+	}, result)
+	assert.Equal(t, "Update to WordPress 4.2.2", runGit(dir, "log", "-1", "--format=%s"))
+	assert.Equal(t, "1.3.7", runGit(dir, "tag", "--list"))
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+}
+
+func TestRunBumpTo(t *testing.T) {
+	dir, _ := makeGitFixture("bumpTo")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+	tags := testTags("4.2.3", "4.2.2", "4.2.1", "4.2")
+
+	_, err := RunBump(b, tags, BumpOptions{To: "4.2.9"})
+	assert.Equal(t, "WordPress 4.2.9 is not a known release", err.Error())
+
+	result, err := RunBump(b, tags, BumpOptions{To: "4.2.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.2", result.NewWordPressVersion)
+}
+
+func TestRunBumpDowngrade(t *testing.T) {
+	dir, _ := makeGitFixture("downgrade")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+	tags := testTags("4.2.2", "4.2.1", "4.2")
+
+	result, err := RunBump(b, tags, BumpOptions{To: "4.2"})
+	assert.Equal(t, "WordPress 4.2 is older than 4.2.1, pass --allow-downgrade to downgrade", err.Error())
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+
+	result, err = RunBump(b, tags, BumpOptions{
+		To:             "4.2",
+		AllowDowngrade: true,
+		Git:            bedrock.GitOptions{Commit: true},
+		ReleaseNotes:   "-",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2", result.NewWordPressVersion)
+	assert.Equal(t, "* Downgrade to WordPress 4.2\n", result.ReleaseNotes)
+	assert.Equal(t, "Downgrade to WordPress 4.2", runGit(dir, "log", "-1", "--format=%s"))
+}
+
+func TestPrintJSON(t *testing.T) {
+	output := captureStdout(func() {
+		Print(OutputJSON, "ignored", bedrock.Result{Status: bedrock.StatusCurrent})
+	})
+	assert.Contains(t, output, `"status": "current"`)
+	assert.Contains(t, output, `"old_wordpress_version": ""`)
+
+	output = captureStdout(func() {
+		Print(OutputText, "text\n", nil)
+	})
+	assert.Equal(t, "text\n", output)
+}
+
+func TestPrintBumpReleaseNotes(t *testing.T) {
+	stderr, _ := ioutil.TempFile("", "bump-bedrock-stderr")
+	defer os.Remove(stderr.Name())
+	old := os.Stderr
+	os.Stderr = stderr
+	result := bedrock.Result{Status: bedrock.StatusUpdated, ReleaseNotes: "* Update to WordPress 4.2.2\n"}
+
+	output := captureStdout(func() {
+		PrintBump(OutputText, result)
+	})
+	os.Stderr = old
+	stderr.Close()
+
+	assert.Equal(t, "* Update to WordPress 4.2.2\n", output)
+	status, _ := ioutil.ReadFile(stderr.Name())
+	assert.Equal(t, "updated successfully\n", string(status))
+
+	output = captureStdout(func() {
+		PrintBump(OutputJSON, result)
+	})
+	assert.Contains(t, output, `"release_notes": "* Update to WordPress 4.2.2\n"`)
+}
+
+func TestRunBumpHooks(t *testing.T) {
+	dir, _ := makeGitFixture("hooks")
+	fakeComposer(dir + "-bin")
+	log := dir + "-hooks.log"
+	b := bedrock.NewBedrock(dir)
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Hooks: bedrock.Hooks{
+			bedrock.HookPreCheck:   `echo "$BUMP_BEDROCK_HOOK $BUMP_BEDROCK_REPO" >> ` + log,
+			bedrock.HookPreWrite:   `echo "$BUMP_BEDROCK_HOOK $BUMP_BEDROCK_OLD_WORDPRESS_VERSION $BUMP_BEDROCK_NEW_WORDPRESS_VERSION" >> ` + log,
+			bedrock.HookPostWrite:  `echo "$BUMP_BEDROCK_NEW_WORDPRESS_VERSION" > wp-version.txt`,
+			bedrock.HookPostCommit: `echo "$BUMP_BEDROCK_HOOK $BUMP_BEDROCK_NEW_PROJECT_VERSION" $BUMP_BEDROCK_FILES_CHANGED >> ` + log,
+		},
+		Git: bedrock.GitOptions{Commit: true},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"composer.json", "CHANGELOG.md", "wp-version.txt"}, result.FilesChanged)
+	assert.Equal(t, []string{
+		"ran pre-check hook",
+		"ran pre-write hook",
+		"ran composer.phar require johnpbloch/wordpress 4.2.2 --no-update --no-progress",
+		"updated CHANGELOG.md",
+		"ran post-write hook",
+		"ran git add -- composer.json CHANGELOG.md wp-version.txt",
+		"ran git commit -m Update to WordPress 4.2.2",
+		"ran post-commit hook",
+	}, result.Actions)
+	out, _ := ioutil.ReadFile(log)
+	assert.Equal(t, "pre-check "+dir+"\npre-write 4.2.1 4.2.2\npost-commit 1.3.7 composer.json CHANGELOG.md wp-version.txt\n", string(out))
+	assert.Equal(t, "CHANGELOG.md\ncomposer.json\nwp-version.txt", runGit(dir, "show", "--format=", "--name-only", "HEAD"))
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+}
+
+func TestRunBumpPreHookAborts(t *testing.T) {
+	dir, _ := makeGitFixture("preHook")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Hooks: bedrock.Hooks{bedrock.HookPreWrite: "echo lint failed; exit 3"},
+	})
+
+	assert.Equal(t, `pre-write hook "echo lint failed; exit 3": exit status 3: lint failed`, err.Error())
+	assert.Equal(t, bedrock.StatusFailed, result.Status)
+	assert.Equal(t, []string{"ran pre-write hook"}, result.Actions)
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+	assert.Equal(t, ExitHook, ExitCode(err))
+}
+
+func TestRunBumpPostHookRollsBack(t *testing.T) {
+	dir, _ := makeGitFixture("postHook")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+	before, _ := ioutil.ReadFile(b.ChangelogPath())
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Hooks: bedrock.Hooks{bedrock.HookPostWrite: "echo lock >> composer.json; echo FROM php > Dockerfile; exit 1"},
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, bedrock.StatusFailed, result.Status)
+	assert.Empty(t, result.FilesChanged)
+	assert.Contains(t, result.Actions, "rolled back composer.json")
+	assert.Contains(t, result.Actions, "rolled back Dockerfile")
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+
+	// files a pre-write hook changed are restored too
+	result, err = RunBump(b, testTags("4.2.2"), BumpOptions{
+		Hooks: bedrock.Hooks{bedrock.HookPreWrite: "echo broken >> CHANGELOG.md; exit 1"},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"ran pre-write hook", "rolled back CHANGELOG.md"}, result.Actions)
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+
+	head := runGit(dir, "rev-parse", "HEAD")
+	branch := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	for _, git := range []bedrock.GitOptions{
+		{Commit: true, Tag: true},
+		{Branch: DefaultPullRequestBranch, Commit: true, Tag: true},
+	} {
+		result, err = RunBump(b, testTags("4.2.2"), BumpOptions{
+			Hooks: bedrock.Hooks{bedrock.HookPostCommit: "exit 1"},
+			Git:   git,
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, bedrock.StatusFailed, result.Status)
+		assert.Contains(t, result.Actions, "undid commit")
+		assert.Equal(t, head, runGit(dir, "rev-parse", "HEAD"))
+		assert.Equal(t, branch, runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"))
+		assert.Equal(t, branch, runGit(dir, "branch", "--format=%(refname:short)"))
+		assert.Equal(t, "", runGit(dir, "tag", "--list"))
+		assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+		after, _ := ioutil.ReadFile(b.ChangelogPath())
+		assert.Equal(t, string(before), string(after))
+	}
+}
+
+func TestRunBumpTagFailureRollsBack(t *testing.T) {
+	dir, _ := makeGitFixture("tagFails")
+	fakeComposer(dir + "-bin")
+	b := bedrock.NewBedrock(dir)
+	runGit(dir, "tag", "1.3.7")
+	head := runGit(dir, "rev-parse", "HEAD")
+	branch := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+
+	result, err := RunBump(b, testTags("4.2.2"), BumpOptions{
+		Git: bedrock.GitOptions{Branch: DefaultPullRequestBranch, Commit: true, Tag: true},
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, bedrock.StatusFailed, result.Status)
+	assert.Contains(t, result.Actions, "undid commit")
+	assert.Equal(t, head, runGit(dir, "rev-parse", "HEAD"))
+	assert.Equal(t, branch, runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"))
+	assert.Equal(t, branch, runGit(dir, "branch", "--format=%(refname:short)"))
+	assert.Equal(t, head, runGit(dir, "rev-list", "-n1", "1.3.7"))
+	assert.Equal(t, "", runGit(dir, "status", "--porcelain"))
+	assert.Equal(t, "4.2.1", b.WordPressVersion())
+}
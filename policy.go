@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"strings"
+	"time"
+)
+
+// Policy decides which newer WordPress releases a bump may adopt. The zero
+// Policy accepts every stable release.
+type Policy struct {
+	// Ignore lists releases that are never adopted, security or not.
+	Ignore []string
+	// Hold is a Composer constraint, e.g. "<4.3", every adopted release
+	// must satisfy.
+	Hold string
+	// MinAge is how many days a release must have been out.
+	MinAge int
+	// AllowSecurity lets security releases through Hold and MinAge.
+	AllowSecurity bool
+	Security      SecurityReleases
+	Now           bedrock.Clock
+}
+
+// Decision is the verdict of a policy on one candidate release.
+type Decision struct {
+	Version  string   `json:"version"`
+	Accepted bool     `json:"accepted"`
+	Reasons  []string `json:"reasons"`
+
+	// Until is when a release rejected only for its age meets the policy.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Evaluate judges every stable release newer than current, newest first.
+// Release dates are only fetched when MinAge is set.
+func (p Policy) Evaluate(tags Tags, current string) ([]Decision, error) {
+	candidates := tags.Newer(current)
+	decisions := []Decision{}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		d, err := p.decide(tags, candidates[i])
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
+
+func (p Policy) decide(tags Tags, v string) (Decision, error) {
+	d := Decision{Version: v, Reasons: []string{}}
+	for _, ignored := range p.Ignore {
+		if version.Compare(v, ignored, "==") {
+			d.Reasons = append(d.Reasons, "ignored by policy")
+			return d, nil
+		}
+	}
+
+	held := []string{}
+	if p.Hold != "" && !version.NewConstrainGroupFromString(p.Hold).Match(v) {
+		held = append(held, fmt.Sprintf("does not satisfy hold %q", p.Hold))
+	}
+	var until *time.Time
+	if p.MinAge > 0 {
+		tag, _ := tags.Find(v)
+		released, err := ReleaseDate(tag)
+		if err != nil {
+			return d, err
+		}
+		now := time.Now
+		if p.Now != nil {
+			now = p.Now
+		}
+		if age := int(now().Sub(released).Hours() / 24); age < p.MinAge {
+			held = append(held, fmt.Sprintf("released %d day(s) ago, policy requires %d", age, p.MinAge))
+			if len(held) == 1 {
+				t := released.AddDate(0, 0, p.MinAge)
+				until = &t
+			}
+		}
+	}
+
+	switch {
+	case len(held) == 0:
+		d.Accepted = true
+		if p.Security[v] {
+			d.Reasons = append(d.Reasons, "security release")
+		} else {
+			d.Reasons = append(d.Reasons, "meets policy")
+		}
+	case p.AllowSecurity && p.Security[v]:
+		d.Accepted = true
+		d.Reasons = append(d.Reasons, "security release allowed despite: "+strings.Join(held, "; "))
+	default:
+		d.Reasons = held
+		d.Until = until
+	}
+	return d, nil
+}
+
+// Select returns the newest release the policy accepts, or current when it
+// accepts none, with every decision made.
+func (p Policy) Select(tags Tags, current string) (string, []Decision, error) {
+	decisions, err := p.Evaluate(tags, current)
+	if err != nil {
+		return current, decisions, err
+	}
+	for _, d := range decisions {
+		if d.Accepted {
+			return d.Version, decisions, nil
+		}
+	}
+	return current, decisions, nil
+}
+
+// Explanation is what the explain command reports.
+type Explanation struct {
+	Repo             string     `json:"repo"`
+	WordPressVersion string     `json:"wordpress_version"`
+	Selected         string     `json:"selected"`
+	Decisions        []Decision `json:"decisions"`
+}
+
+// Explain evaluates policy for the repository b.
+func Explain(b bedrock.BedrockRepoInstance, tags Tags, policy Policy) (Explanation, error) {
+	e := Explanation{Repo: b.Path(), Decisions: []Decision{}}
+	current, err := b.ReadWordPressVersion()
+	if err != nil {
+		return e, err
+	}
+	e.WordPressVersion = current
+	e.Selected, e.Decisions, err = policy.Select(tags, current)
+	return e, err
+}
+
+// ExplainText renders an explanation for the terminal.
+func ExplainText(e Explanation) string {
+	if len(e.Decisions) == 0 {
+		return fmt.Sprintf("WordPress %s: no newer releases\n", e.WordPressVersion)
+	}
+	text := fmt.Sprintf("WordPress %s, candidates:\n", e.WordPressVersion)
+	for _, d := range e.Decisions {
+		verdict := "rejected"
+		if d.Accepted {
+			verdict = "accepted"
+		}
+		text += fmt.Sprintf("  %-10s %s: %s\n", d.Version, verdict, strings.Join(d.Reasons, "; "))
+	}
+	if e.Selected == e.WordPressVersion {
+		return text + "bump would keep " + e.Selected + "\n"
+	}
+	return text + "bump would choose " + e.Selected + "\n"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// datedTags serves a commit date for every release and returns tags
+// pointing at them.
+func datedTags(dates map[string]string) (Tags, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"commit": {"committer": {"date": "%sT12:00:00Z"}}}`, dates[r.URL.Path[1:]])
+	}))
+	tags := Tags{}
+	for _, name := range []string{"4.3", "4.2.4", "4.2.3", "4.2.2", "4.2.1"} {
+		tags = append(tags, Tag{Name: name, Commit: Commit{Url: ts.URL + "/" + name}})
+	}
+	return tags, ts
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	tags, ts := datedTags(map[string]string{"4.3": "2015-08-05", "4.2.4": "2015-08-04", "4.2.3": "2015-07-23", "4.2.2": "2015-05-07"})
+	defer ts.Close()
+	p := Policy{
+		Ignore:        []string{"4.2.3"},
+		Hold:          "<4.3",
+		MinAge:        7,
+		AllowSecurity: true,
+		Security:      SecurityReleases{"4.2.3": true, "4.2.4": true},
+		Now:           bedrock.FixedClock(time.Date(2015, 8, 6, 12, 0, 0, 0, time.UTC)),
+	}
+
+	target, decisions, err := p.Select(tags, "4.2.1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.4", target)
+	assert.Equal(t, []Decision{
+		{Version: "4.3", Reasons: []string{`does not satisfy hold "<4.3"`, "released 1 day(s) ago, policy requires 7"}},
+		{Version: "4.2.4", Accepted: true, Reasons: []string{"security release allowed despite: released 2 day(s) ago, policy requires 7"}},
+		{Version: "4.2.3", Reasons: []string{"ignored by policy"}},
+		{Version: "4.2.2", Accepted: true, Reasons: []string{"meets policy"}},
+	}, decisions)
+
+	p.AllowSecurity = false
+	target, _, _ = p.Select(tags, "4.2.1")
+	assert.Equal(t, "4.2.2", target)
+
+	// a release held back only by its age says when it will be accepted
+	p.Hold = ""
+	_, decisions, _ = p.Select(tags, "4.2.1")
+	assert.Equal(t, time.Date(2015, 8, 12, 12, 0, 0, 0, time.UTC), *decisions[0].Until)
+	_, err = ReleaseTarget(tags, "4.3", "4.2.1", p)
+	recent, ok := err.(*TooRecentError)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2015, 8, 12, 12, 0, 0, 0, time.UTC), recent.Until)
+	assert.Equal(t, "WordPress 4.3 is rejected by policy: released 1 day(s) ago, policy requires 7", err.Error())
+}
+
+func TestPolicyKeepsCurrent(t *testing.T) {
+	target, decisions, err := Policy{Ignore: []string{"4.2.2"}}.Select(testTags("4.2.2", "4.2.1"), "4.2.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.1", target)
+	assert.Equal(t, 1, len(decisions))
+}
+
+func TestRunBumpPolicy(t *testing.T) {
+	dir, _ := makeGitFixture("policy")
+	fakeComposer(dir + "-bin")
+
+	result, err := RunBump(bedrock.NewBedrock(dir), testTags("4.3", "4.2.2", "4.2.1"), BumpOptions{Policy: Policy{Hold: "~4.2.0"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.2", result.NewWordPressVersion)
+}
+
+func TestExplain(t *testing.T) {
+	dir, _ := makeGitFixture("explain")
+
+	e, err := Explain(bedrock.NewBedrock(dir), testTags("4.3", "4.2.2"), Policy{Ignore: []string{"4.2.2"}, Hold: "<4.3"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `WordPress 4.2.1, candidates:
+  4.3        rejected: does not satisfy hold "<4.3"
+  4.2.2      rejected: ignored by policy
+bump would keep 4.2.1
+`, ExplainText(e))
+}
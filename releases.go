@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecurityReleases is the set of WordPress versions known to be security
+// releases.
+type SecurityReleases map[string]bool
+
+// ParseSecurityReleases reads a comma separated list of versions.
+func ParseSecurityReleases(list string) SecurityReleases {
+	s := SecurityReleases{}
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			s[v] = true
+		}
+	}
+	return s
+}
+
+// Versions returns the stable tag names sorted oldest first.
+func (tags Tags) Versions() []string {
+	versions := []string{}
+	for _, tag := range tags {
+		if version.GetStability(tag.Name) == version.Stable {
+			versions = append(versions, tag.Name)
+		}
+	}
+	version.Sort(versions)
+	return versions
+}
+
+// Latest returns the newest stable tag name, or "" when there is none.
+func (tags Tags) Latest() string {
+	versions := tags.Versions()
+	if len(versions) == 0 {
+		return ""
+	}
+	return versions[len(versions)-1]
+}
+
+// Between returns the stable versions newer than from and older than to,
+// oldest first.
+func (tags Tags) Between(from, to string) []string {
+	between := []string{}
+	for _, v := range tags.Versions() {
+		if version.Compare(v, from, ">") && version.Compare(v, to, "<") {
+			between = append(between, v)
+		}
+	}
+	return between
+}
+
+// Newer returns the stable versions newer than from, oldest first.
+func (tags Tags) Newer(from string) []string {
+	newer := []string{}
+	for _, v := range tags.Versions() {
+		if version.Compare(v, from, ">") {
+			newer = append(newer, v)
+		}
+	}
+	return newer
+}
+
+// Find returns the tag called name.
+func (tags Tags) Find(name string) (Tag, bool) {
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+// TargetVersion is the release a bump from current moves to: to, which
+// must be one of tags and bypasses policy, or else the newest release
+// policy accepts.
+func TargetVersion(tags Tags, to, current string, policy Policy) (string, error) {
+	if to == "" {
+		target, _, err := policy.Select(tags, current)
+		return target, err
+	}
+	if _, ok := tags.Find(to); !ok {
+		return "", usageErrorf("WordPress %s is not a known release", to)
+	}
+	return to, nil
+}
+
+// ReleaseTarget is release, which must be one of tags, when policy accepts
+// it as a bump from current. A release no newer than current, such as a
+// security backport to an older branch, leaves current in place.
+func ReleaseTarget(tags Tags, release, current string, policy Policy) (string, error) {
+	if _, ok := tags.Find(release); !ok {
+		return "", fmt.Errorf("WordPress %s is not a known release", release)
+	}
+	if !version.Compare(release, current, ">") {
+		return current, nil
+	}
+	d, err := policy.decide(tags, release)
+	if err != nil {
+		return "", err
+	}
+	if !d.Accepted {
+		err := fmt.Errorf("WordPress %s is rejected by policy: %s", release, strings.Join(d.Reasons, "; "))
+		if d.Until != nil {
+			return "", &TooRecentError{Err: err, Until: *d.Until}
+		}
+		return "", err
+	}
+	return release, nil
+}
+
+// TooRecentError is a policy rejection of a release that is only too
+// recent, and that the policy accepts from Until.
+type TooRecentError struct {
+	Err   error
+	Until time.Time
+}
+
+func (e *TooRecentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TooRecentError) Unwrap() error {
+	return e.Err
+}
+
+// ReleaseDate fetches the date of the commit tag points at. Every failure
+// is a *NetworkError.
+func ReleaseDate(tag Tag) (time.Time, error) {
+	response, err := http.Get(tag.Commit.Url)
+	if err != nil {
+		return time.Time{}, &NetworkError{err}
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return time.Time{}, &NetworkError{fmt.Errorf("GET %s: %s", tag.Commit.Url, response.Status)}
+	}
+	var commit struct {
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&commit); err != nil {
+		return time.Time{}, &NetworkError{fmt.Errorf("GET %s: %s", tag.Commit.Url, err)}
+	}
+	return commit.Commit.Committer.Date, nil
+}
+
+// SkippedReleases lists the releases a bump from one version to another
+// jumps over, marking the security ones.
+func SkippedReleases(tags Tags, security SecurityReleases, from, to string) []bedrock.SkippedRelease {
+	skipped := []bedrock.SkippedRelease{}
+	for _, v := range tags.Between(from, to) {
+		skipped = append(skipped, bedrock.SkippedRelease{Version: v, Security: security[v]})
+	}
+	return skipped
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"testing"
+)
+
+func testTags(names ...string) Tags {
+	tags := Tags{}
+	for _, name := range names {
+		tags = append(tags, Tag{Name: name})
+	}
+	return tags
+}
+
+func TestParseSecurityReleases(t *testing.T) {
+	s := ParseSecurityReleases(" 4.2.2, 4.2.3,,")
+	assert.Equal(t, SecurityReleases{"4.2.2": true, "4.2.3": true}, s)
+}
+
+func TestTagsVersions(t *testing.T) {
+	tags := testTags("4.2.4", "4.3-beta1", "4.2.10", "4.2.2", "4.2.3")
+	assert.Equal(t, []string{"4.2.2", "4.2.3", "4.2.4", "4.2.10"}, tags.Versions())
+	assert.Equal(t, []string{"4.2.3", "4.2.4"}, tags.Between("4.2.2", "4.2.10"))
+	assert.Equal(t, "4.2.10", tags.Latest())
+	assert.Equal(t, "", testTags("4.3-beta1").Latest())
+}
+
+func TestSkippedReleases(t *testing.T) {
+	tags := testTags("4.2.4", "4.2.3", "4.2.2", "4.2.1")
+	skipped := SkippedReleases(tags, SecurityReleases{"4.2.2": true}, "4.2.1", "4.2.4")
+	assert.Equal(t, []bedrock.SkippedRelease{
+		{Version: "4.2.2", Security: true},
+		{Version: "4.2.3", Security: false},
+	}, skipped)
+}
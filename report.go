@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report formats.
+const (
+	ReportMarkdown = "markdown"
+	ReportHTML     = "html"
+	ReportCSV      = "csv"
+)
+
+// ReportRow is the version drift of one repository. DaysSinceBump is -1
+// when the changelog has no dated WordPress bump.
+type ReportRow struct {
+	Name             string   `json:"name"`
+	Repo             string   `json:"repo"`
+	WordPressVersion string   `json:"wordpress_version"`
+	LatestVersion    string   `json:"latest_version"`
+	Behind           int      `json:"behind"`
+	LastBump         string   `json:"last_bump"`
+	DaysSinceBump    int      `json:"days_since_bump"`
+	SecurityReleases []string `json:"security_releases"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// Report is the version drift of a fleet.
+type Report struct {
+	LatestVersion string      `json:"latest_version"`
+	Rows          []ReportRow `json:"repos"`
+}
+
+// RepoOpener opens a repository of a fleet with its configuration applied
+// and returns its known security releases.
+type RepoOpener func(path string) (bedrock.BedrockRepoInstance, SecurityReleases, error)
+
+// BuildReport checks every repository against tags. A repository that
+// cannot be read gets a row with its error.
+func BuildReport(repos []ManifestRepo, tags Tags, open RepoOpener) Report {
+	report := Report{LatestVersion: tags.Latest(), Rows: []ReportRow{}}
+	for _, repo := range repos {
+		row := ReportRow{Name: repo.Name, Repo: repo.Path, DaysSinceBump: -1, SecurityReleases: []string{}}
+		if err := fillReportRow(&row, repo, tags, open); err != nil {
+			row.Error = err.Error()
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report
+}
+
+func fillReportRow(row *ReportRow, repo ManifestRepo, tags Tags, open RepoOpener) error {
+	b, security, err := open(repo.Path)
+	if err != nil {
+		return err
+	}
+	check, err := Check(b, tags, CheckOptions{Security: security})
+	if err != nil {
+		return err
+	}
+	row.WordPressVersion = check.WordPressVersion
+	row.LatestVersion = check.LatestVersion
+	row.Behind = len(check.Behind)
+	row.SecurityReleases = check.SecurityReleases
+
+	changelog, err := b.ReadChangelog()
+	if err != nil {
+		return err
+	}
+	release, ok := LastWordPressBump(b, changelog)
+	if !ok || release.Date == "" {
+		return nil
+	}
+	row.LastBump = release.Date
+	loc := b.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	date, err := time.ParseInLocation(b.DateLayout, release.Date, loc)
+	if err != nil {
+		return fmt.Errorf("%s: release %s: %s", b.ChangelogPath(), release.Version, err)
+	}
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	row.DaysSinceBump = int(now().Sub(date).Hours() / 24)
+	return nil
+}
+
+// LastWordPressBump is the newest released changelog section with an
+// update or downgrade entry of b's templates.
+func LastWordPressBump(b bedrock.BedrockRepoInstance, c bedrock.Changelog) (bedrock.Release, bool) {
+	for _, r := range c.Releases {
+		if r.Version == bedrock.Unreleased {
+			continue
+		}
+		for _, note := range r.Notes {
+			if b.IsVersionEntry(note) {
+				return r, true
+			}
+		}
+	}
+	return bedrock.Release{}, false
+}
+
+// reportColumns are the headings of every report format.
+var reportColumns = []string{"Site", "WordPress", "Latest", "Behind", "Last bump", "Days since bump", "Security releases", "Error"}
+
+func (r ReportRow) cells() []string {
+	days := ""
+	if r.DaysSinceBump >= 0 {
+		days = strconv.Itoa(r.DaysSinceBump)
+	}
+	return []string{
+		r.Name,
+		r.WordPressVersion,
+		r.LatestVersion,
+		strconv.Itoa(r.Behind),
+		r.LastBump,
+		days,
+		strings.Join(r.SecurityReleases, ", "),
+		r.Error,
+	}
+}
+
+// Render renders the report as ReportMarkdown, ReportHTML or ReportCSV.
+func (r Report) Render(format string) (string, error) {
+	switch format {
+	case ReportMarkdown:
+		return r.markdown(), nil
+	case ReportHTML:
+		return r.html()
+	case ReportCSV:
+		return r.csv()
+	}
+	return "", usageErrorf("unknown report format %q, expected markdown, html or csv", format)
+}
+
+func (r Report) markdown() string {
+	escape := strings.NewReplacer("|", `\|`)
+	row := func(cells []string) string {
+		for i, c := range cells {
+			cells[i] = escape.Replace(c)
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	out := row(append([]string{}, reportColumns...))
+	out += strings.Repeat("| --- ", len(reportColumns)) + "|\n"
+	for _, repo := range r.Rows {
+		out += row(repo.cells())
+	}
+	return out
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<table>
+<thead>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+`))
+
+func (r Report) html() (string, error) {
+	rows := [][]string{}
+	for _, repo := range r.Rows {
+		rows = append(rows, repo.cells())
+	}
+	var buf bytes.Buffer
+	err := reportHTML.Execute(&buf, map[string]interface{}{"Columns": reportColumns, "Rows": rows})
+	return buf.String(), err
+}
+
+func (r Report) csv() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(reportColumns)
+	for _, repo := range r.Rows {
+		w.Write(repo.cells())
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"testing"
+	"time"
+)
+
+func testReport(t *testing.T) Report {
+	dir, _ := makeGitFixture("report")
+	open := func(path string) (bedrock.BedrockRepoInstance, SecurityReleases, error) {
+		if path == "missing" {
+			return bedrock.BedrockRepoInstance{}, nil, errors.New("open missing/composer.json: no such file or directory")
+		}
+		b := bedrock.NewBedrock(path)
+		b.Now = bedrock.FixedClock(time.Date(2015, 5, 27, 12, 0, 0, 0, time.Local))
+		return b, SecurityReleases{"4.2.2": true}, nil
+	}
+	return BuildReport([]ManifestRepo{{Name: "acme", Path: dir}, {Name: "gone", Path: "missing"}}, testTags("4.3-RC1", "4.2.2", "4.2.3", "4.2.1"), open)
+}
+
+func TestBuildReport(t *testing.T) {
+	report := testReport(t)
+
+	assert.Equal(t, "4.2.3", report.LatestVersion)
+	row := report.Rows[0]
+	assert.Equal(t, "4.2.1", row.WordPressVersion)
+	assert.Equal(t, 2, row.Behind)
+	assert.Equal(t, "2015-04-27", row.LastBump)
+	assert.Equal(t, 30, row.DaysSinceBump)
+	assert.Equal(t, []string{"4.2.2"}, row.SecurityReleases)
+	assert.Equal(t, -1, report.Rows[1].DaysSinceBump)
+	assert.Equal(t, "open missing/composer.json: no such file or directory", report.Rows[1].Error)
+}
+
+func TestReportRender(t *testing.T) {
+	report := testReport(t)
+
+	md, err := report.Render(ReportMarkdown)
+	assert.Nil(t, err)
+	assert.Equal(t, `| Site | WordPress | Latest | Behind | Last bump | Days since bump | Security releases | Error |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+| acme | 4.2.1 | 4.2.3 | 2 | 2015-04-27 | 30 | 4.2.2 |  |
+| gone |  |  | 0 |  |  |  | open missing/composer.json: no such file or directory |
+`, md)
+
+	csv, err := report.Render(ReportCSV)
+	assert.Nil(t, err)
+	assert.Contains(t, csv, "acme,4.2.1,4.2.3,2,2015-04-27,30,4.2.2,\n")
+
+	report.Rows[0].Name = "<acme>"
+	html, err := report.Render(ReportHTML)
+	assert.Nil(t, err)
+	assert.Contains(t, html, "<tr><td>&lt;acme&gt;</td><td>4.2.1</td><td>4.2.3</td><td>2</td>")
+
+	_, err = report.Render("pdf")
+	assert.NotNil(t, err)
+}
+
+func TestLastWordPressBump(t *testing.T) {
+	changelog := bedrock.ParseChangelog(`### HEAD
+
+* Update to WordPress 4.3
+
+### 1.4.0: 2015-06-01
+
+* Document WordPress multisite
+* WordPress 4.2.1 fix
+
+### 1.3.9: 2015-05-20
+
+* Downgrade to WordPress 4.2.1
+
+### 1.3.8: 2015-05-10
+
+* Bump WP core to 4.2.2
+`)
+	b := bedrock.NewBedrock(".")
+
+	release, ok := LastWordPressBump(b, changelog)
+	assert.True(t, ok)
+	assert.Equal(t, "1.3.9", release.Version)
+
+	b.UpdateEntry = "Bump WP core to {{.WordPressVersion}}"
+	b.DowngradeEntry = "{{.Action}} WP core to {{.WordPressVersion}}"
+	release, ok = LastWordPressBump(b, changelog)
+	assert.True(t, ok)
+	assert.Equal(t, "1.3.9", release.Version)
+
+	custom := bedrock.ParseChangelog("### 1.0.0: 2015-01-01\n\n* Bump WP core to 4.2.2\n")
+	_, ok = LastWordPressBump(b, custom)
+	assert.True(t, ok)
+	_, ok = LastWordPressBump(bedrock.NewBedrock("."), custom)
+	assert.False(t, ok)
+}
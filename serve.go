@@ -0,0 +1,415 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is a queued bump of one repository, triggered by a release.
+type Job struct {
+	Repo    ManifestRepo `json:"repo"`
+	Version string       `json:"version"`
+}
+
+// Queue is a de-duplicated queue of bump jobs kept on disk, so jobs queued
+// before a restart are still run after it. A job stays pending until it
+// is finished; finished jobs are remembered so a redelivered or second
+// event for the same release does not bump a repository again, and failed
+// jobs are kept for inspection. Deferred jobs wait until a time before
+// they are pending again.
+type Queue struct {
+	path  string
+	state queueState
+	mu    sync.Mutex
+	wake  chan struct{}
+}
+
+// queueState is what a Queue stores on disk.
+type queueState struct {
+	Pending  []Job         `json:"pending"`
+	Deferred []DeferredJob `json:"deferred"`
+	Done     []Job         `json:"done"`
+	Failed   []FailedJob   `json:"failed"`
+}
+
+// DeferredJob is a job that waits until Until before it runs.
+type DeferredJob struct {
+	Job
+	Until time.Time `json:"until"`
+}
+
+// FailedJob is a job whose bump failed.
+type FailedJob struct {
+	Job
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// OpenQueue loads the queue stored at path. A missing file is an empty
+// queue.
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{
+		path:  path,
+		state: queueState{Pending: []Job{}, Deferred: []DeferredJob{}, Done: []Job{}, Failed: []FailedJob{}},
+		wake:  make(chan struct{}, 1),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.state); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return q, nil
+}
+
+// Add queues every job that is neither pending, deferred nor done and
+// returns how many were added. A failed job can be queued again.
+func (q *Queue) Add(jobs ...Job) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	added := 0
+	for _, job := range jobs {
+		if indexJob(q.state.Pending, job) < 0 && indexDeferred(q.state.Deferred, job) < 0 && indexJob(q.state.Done, job) < 0 {
+			q.state.Pending = append(q.state.Pending, job)
+			added++
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := q.save(); err != nil {
+		q.state.Pending = q.state.Pending[:len(q.state.Pending)-added]
+		return 0, err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return added, nil
+}
+
+// Jobs lists the pending jobs, oldest first.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Job{}, q.state.Pending...)
+}
+
+// Failed lists the jobs that failed, oldest first.
+func (q *Queue) Failed() []FailedJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]FailedJob{}, q.state.Failed...)
+}
+
+// Deferred lists the deferred jobs, oldest first.
+func (q *Queue) Deferred() []DeferredJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]DeferredJob{}, q.state.Deferred...)
+}
+
+// Defer moves job from the pending jobs to the deferred ones until until.
+func (q *Queue) Defer(job Job, until time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := indexJob(q.state.Pending, job); i >= 0 {
+		q.state.Pending = append(q.state.Pending[:i:i], q.state.Pending[i+1:]...)
+	}
+	q.state.Deferred = append(q.state.Deferred, DeferredJob{Job: job, Until: until})
+	return q.save()
+}
+
+// ready makes the deferred jobs due at now pending again and returns how
+// long until the next one is due, or 0 when none is deferred.
+func (q *Queue) ready(now time.Time) (time.Duration, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	waiting := []DeferredJob{}
+	next := time.Duration(0)
+	for _, job := range q.state.Deferred {
+		if !job.Until.After(now) {
+			q.state.Pending = append(q.state.Pending, job.Job)
+			continue
+		}
+		waiting = append(waiting, job)
+		if wait := job.Until.Sub(now); next == 0 || wait < next {
+			next = wait
+		}
+	}
+	if len(waiting) == len(q.state.Deferred) {
+		return next, nil
+	}
+	q.state.Deferred = waiting
+	return next, q.save()
+}
+
+// Finish removes job from the pending jobs and records it as done, or as
+// failed when err is not nil.
+func (q *Queue) Finish(job Job, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := indexJob(q.state.Pending, job); i >= 0 {
+		q.state.Pending = append(q.state.Pending[:i:i], q.state.Pending[i+1:]...)
+	}
+	if err != nil {
+		q.state.Failed = append(q.state.Failed, FailedJob{Job: job, Error: err.Error(), FailedAt: time.Now()})
+	} else {
+		q.state.Done = append(q.state.Done, job)
+	}
+	return q.save()
+}
+
+// Work runs the pending jobs one at a time, oldest first, until stop is
+// closed. A failed job is recorded as failed rather than retried, so a
+// broken repository is not bumped over and over. A job whose release the
+// policy only rejects for being too recent is deferred until it is old
+// enough.
+func (q *Queue) Work(stop <-chan struct{}, run func(Job) error, logger *log.Logger) {
+	for {
+		next, err := q.ready(time.Now())
+		if err != nil && logger != nil {
+			logger.Printf("saving queue: %s", err)
+		}
+		jobs := q.Jobs()
+		if len(jobs) == 0 {
+			var due <-chan time.Time
+			if next > 0 {
+				due = time.After(next)
+			}
+			select {
+			case <-stop:
+				return
+			case <-q.wake:
+			case <-due:
+			}
+			continue
+		}
+		job := jobs[0]
+		err = run(job)
+		var recent *TooRecentError
+		if errors.As(err, &recent) {
+			if logger != nil {
+				logger.Printf("deferring %s to %s until %s: %s", job.Repo.Name, job.Version, recent.Until.Format(time.RFC3339), err)
+			}
+			err = q.Defer(job, recent.Until)
+		} else {
+			if err != nil && logger != nil {
+				logger.Printf("bumping %s to %s failed: %s", job.Repo.Name, job.Version, err)
+			}
+			err = q.Finish(job, err)
+		}
+		if err != nil && logger != nil {
+			logger.Printf("saving queue: %s", err)
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+func indexJob(jobs []Job, job Job) int {
+	for i, queued := range jobs {
+		if queued.Repo.Path == job.Repo.Path && queued.Version == job.Version {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexDeferred(jobs []DeferredJob, job Job) int {
+	for i, deferred := range jobs {
+		if deferred.Repo.Path == job.Repo.Path && deferred.Version == job.Version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queue) save() error {
+	data, err := json.MarshalIndent(q.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return bedrock.WriteFileAtomic(q.path, append(data, '\n'))
+}
+
+// JobRunner runs queued jobs.
+type JobRunner struct {
+	Fetch  func() (Tags, error)
+	Bumper func(tags Tags, release string) FleetBumper
+	Report func(FleetResult)
+}
+
+// Run fetches the tags and bumps the job's repository to the job's
+// release, if the repository's policy accepts it.
+func (j JobRunner) Run(job Job) error {
+	tags, err := j.Fetch()
+	if err != nil {
+		return err
+	}
+	result := RunFleet([]ManifestRepo{job.Repo}, 1, j.Bumper(tags, job.Version))
+	if j.Report != nil {
+		j.Report(result)
+	}
+	return result.Repos[0].err
+}
+
+// VerifySignature reports whether signature, the X-Hub-Signature-256
+// header of a webhook delivery, is the HMAC of body with secret.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// TagsRepository is the owner/name of the GitHub repository a tags URL
+// lists, or "" when the URL is not a GitHub API tags URL.
+func TagsRepository(url string) string {
+	m := regexp.MustCompile(`/repos/([^/]+/[^/]+)/tags/?$`).FindStringSubmatch(url)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// webhookPayload holds the fields of create and release events the
+// receiver reads.
+type webhookPayload struct {
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+	Action  string `json:"action"`
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// ReleaseVersion is the WordPress version a webhook event announces, or ""
+// when the event is not a new stable tag or published release of
+// repository.
+// An empty repository accepts events of any repository.
+func ReleaseVersion(event string, body []byte, repository string) (string, error) {
+	p := webhookPayload{}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return "", err
+	}
+	if repository != "" && !strings.EqualFold(p.Repository.FullName, repository) {
+		return "", nil
+	}
+	v := ""
+	switch event {
+	case "create":
+		if p.RefType == "tag" {
+			v = p.Ref
+		}
+	case "release":
+		if p.Action == "published" && !p.Release.Draft && !p.Release.Prerelease {
+			v = p.Release.TagName
+		}
+	}
+	if v == "" || version.GetStability(v) != version.Stable {
+		return "", nil
+	}
+	return v, nil
+}
+
+// WebhookHandler receives GitHub create and release webhooks for the
+// WordPress mirror and queues a bump of every repository for each new
+// release.
+type WebhookHandler struct {
+	Secret     string
+	Repository string
+	Repos      []ManifestRepo
+	Queue      *Queue
+}
+
+// WebhookResponse is the body of a webhook response.
+type WebhookResponse struct {
+	Version string `json:"version,omitempty"`
+	Queued  int    `json:"queued"`
+	Message string `json:"message"`
+}
+
+func (h WebhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		h.respond(w, http.StatusMethodNotAllowed, WebhookResponse{Message: "method not allowed"})
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, 1<<20))
+	if err != nil {
+		h.respond(w, http.StatusBadRequest, WebhookResponse{Message: err.Error()})
+		return
+	}
+	if !VerifySignature(h.Secret, body, req.Header.Get("X-Hub-Signature-256")) {
+		h.respond(w, http.StatusUnauthorized, WebhookResponse{Message: "bad signature"})
+		return
+	}
+
+	event := req.Header.Get("X-GitHub-Event")
+	if event == "ping" {
+		h.respond(w, http.StatusOK, WebhookResponse{Message: "pong"})
+		return
+	}
+	v, err := ReleaseVersion(event, body, h.Repository)
+	if err != nil {
+		h.respond(w, http.StatusBadRequest, WebhookResponse{Message: err.Error()})
+		return
+	}
+	if v == "" {
+		h.respond(w, http.StatusOK, WebhookResponse{Message: "ignored " + event + " event"})
+		return
+	}
+
+	jobs := []Job{}
+	for _, repo := range h.Repos {
+		jobs = append(jobs, Job{Repo: repo, Version: v})
+	}
+	queued, err := h.Queue.Add(jobs...)
+	if err != nil {
+		h.respond(w, http.StatusInternalServerError, WebhookResponse{Version: v, Message: err.Error()})
+		return
+	}
+	h.respond(w, http.StatusAccepted, WebhookResponse{
+		Version: v,
+		Queued:  queued,
+		Message: fmt.Sprintf("queued %d bumps to WordPress %s", queued, v),
+	})
+}
+
+func (h WebhookHandler) respond(w http.ResponseWriter, status int, body WebhookResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
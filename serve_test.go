@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliver(t *testing.T, url, event, signature, body string) (int, WebhookResponse) {
+	req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", signature)
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	out := WebhookResponse{}
+	json.NewDecoder(res.Body).Decode(&out)
+	return res.StatusCode, out
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"ref": "4.2.2"}`)
+	assert.True(t, VerifySignature("s3cret", body, sign("s3cret", body)))
+	assert.False(t, VerifySignature("other", body, sign("s3cret", body)))
+	assert.False(t, VerifySignature("s3cret", []byte(`{"ref": "4.2.3"}`), sign("s3cret", body)))
+	assert.False(t, VerifySignature("s3cret", body, ""))
+	assert.False(t, VerifySignature("s3cret", body, "sha256=zz"))
+}
+
+func TestTagsRepository(t *testing.T) {
+	assert.Equal(t, "johnpbloch/wordpress", TagsRepository("https://api.github.com/repos/johnpbloch/wordpress/tags"))
+	assert.Equal(t, "", TagsRepository("http://localhost:8765/tags.json"))
+}
+
+func TestReleaseVersion(t *testing.T) {
+	repo := `"repository": {"full_name": "johnpbloch/wordpress"}`
+	cases := []struct {
+		event, body, version string
+	}{
+		{"create", `{"ref": "4.2.2", "ref_type": "tag", ` + repo + `}`, "4.2.2"},
+		{"create", `{"ref": "master", "ref_type": "branch", ` + repo + `}`, ""},
+		{"create", `{"ref": "4.3-beta1", "ref_type": "tag", ` + repo + `}`, ""},
+		{"release", `{"action": "published", "release": {"tag_name": "4.2.2"}, ` + repo + `}`, "4.2.2"},
+		{"release", `{"action": "published", "release": {"tag_name": "4.2.2", "draft": true}, ` + repo + `}`, ""},
+		{"release", `{"action": "edited", "release": {"tag_name": "4.2.2"}, ` + repo + `}`, ""},
+		{"push", `{"ref": "4.2.2", ` + repo + `}`, ""},
+		{"create", `{"ref": "4.2.2", "ref_type": "tag", "repository": {"full_name": "someone/else"}}`, ""},
+	}
+	for _, c := range cases {
+		v, err := ReleaseVersion(c.event, []byte(c.body), "johnpbloch/wordpress")
+		assert.Nil(t, err)
+		assert.Equal(t, c.version, v, c.event+" "+c.body)
+	}
+
+	_, err := ReleaseVersion("create", []byte(`nope`), "")
+	assert.NotNil(t, err)
+}
+
+func TestQueue(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-queue")
+	defer os.RemoveAll(dir)
+	a := Job{Repo: ManifestRepo{Name: "a", Path: "/srv/a"}, Version: "4.2.2"}
+	b := Job{Repo: ManifestRepo{Name: "b", Path: "/srv/b"}, Version: "4.2.2"}
+
+	q, err := OpenQueue(dir + "/queue.json")
+	assert.Nil(t, err)
+	added, err := q.Add(a, b, a)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, added)
+	added, _ = q.Add(b)
+	assert.Equal(t, 0, added)
+
+	// the queue survives a restart
+	q, err = OpenQueue(dir + "/queue.json")
+	assert.Nil(t, err)
+	assert.Equal(t, []Job{a, b}, q.Jobs())
+
+	assert.Nil(t, q.Finish(a, nil))
+	assert.Nil(t, q.Finish(b, errors.New("composer failed")))
+	q, _ = OpenQueue(dir + "/queue.json")
+	assert.Empty(t, q.Jobs())
+	assert.Equal(t, 1, len(q.Failed()))
+	assert.Equal(t, b, q.Failed()[0].Job)
+	assert.Equal(t, "composer failed", q.Failed()[0].Error)
+
+	// a finished job is not queued again, a failed one is
+	added, _ = q.Add(a, b)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, []Job{b}, q.Jobs())
+}
+
+func TestQueueWork(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-queue")
+	defer os.RemoveAll(dir)
+	q, _ := OpenQueue(dir + "/queue.json")
+	q.Add(Job{Repo: ManifestRepo{Name: "a", Path: "/srv/a"}, Version: "4.2.2"}, Job{Repo: ManifestRepo{Name: "b", Path: "/srv/b"}, Version: "4.2.2"})
+
+	stop := make(chan struct{})
+	ran := []string{}
+	q.Work(stop, func(job Job) error {
+		ran = append(ran, job.Repo.Name)
+		if len(ran) == 2 {
+			close(stop)
+		}
+		return errors.New("composer failed")
+	}, nil)
+
+	assert.Equal(t, []string{"a", "b"}, ran)
+	assert.Empty(t, q.Jobs())
+	assert.Equal(t, 2, len(q.Failed()))
+}
+
+func TestQueueWorkDefers(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-queue")
+	defer os.RemoveAll(dir)
+	q, _ := OpenQueue(dir + "/queue.json")
+	job := Job{Repo: ManifestRepo{Name: "a", Path: "/srv/a"}, Version: "4.3"}
+	q.Add(job)
+
+	// a deferred job is not queued again
+	assert.Nil(t, q.Defer(job, time.Now().Add(time.Hour)))
+	added, _ := q.Add(job)
+	assert.Equal(t, 0, added)
+	q, _ = OpenQueue(dir + "/queue.json")
+	assert.Equal(t, job, q.Deferred()[0].Job)
+	assert.Empty(t, q.Jobs())
+
+	// a release that is too recent is retried once it is old enough
+	q, _ = OpenQueue(dir + "/queue2.json")
+	q.Add(job)
+	stop := make(chan struct{})
+	runs := 0
+	q.Work(stop, func(Job) error {
+		runs++
+		if runs == 1 {
+			return &TooRecentError{Err: errors.New("too recent"), Until: time.Now().Add(50 * time.Millisecond)}
+		}
+		close(stop)
+		return nil
+	}, nil)
+
+	assert.Equal(t, 2, runs)
+	assert.Empty(t, q.Jobs())
+	assert.Empty(t, q.Deferred())
+	assert.Empty(t, q.Failed())
+	added, _ = q.Add(job)
+	assert.Equal(t, 0, added)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-queue")
+	defer os.RemoveAll(dir)
+	q, _ := OpenQueue(dir + "/queue.json")
+	server := httptest.NewServer(WebhookHandler{
+		Secret:     "s3cret",
+		Repository: "johnpbloch/wordpress",
+		Repos:      []ManifestRepo{{Name: "a", Path: "/srv/a"}, {Name: "b", Path: "/srv/b"}},
+		Queue:      q,
+	})
+	defer server.Close()
+	release := `{"ref": "4.2.2", "ref_type": "tag", "repository": {"full_name": "johnpbloch/wordpress"}}`
+
+	status, _ := deliver(t, server.URL, "create", sign("wrong", []byte(release)), release)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Empty(t, q.Jobs())
+
+	status, out := deliver(t, server.URL, "ping", sign("s3cret", []byte(`{}`)), `{}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "pong", out.Message)
+
+	status, out = deliver(t, server.URL, "create", sign("s3cret", []byte(release)), release)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, WebhookResponse{Version: "4.2.2", Queued: 2, Message: "queued 2 bumps to WordPress 4.2.2"}, out)
+	assert.Equal(t, 2, len(q.Jobs()))
+
+	// the release event for the same tag is a duplicate, also once the
+	// bumps are done
+	published := `{"action": "published", "release": {"tag_name": "4.2.2"}, "repository": {"full_name": "johnpbloch/wordpress"}}`
+	status, out = deliver(t, server.URL, "release", sign("s3cret", []byte(published)), published)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, 0, out.Queued)
+	assert.Equal(t, 2, len(q.Jobs()))
+	for _, job := range q.Jobs() {
+		q.Finish(job, nil)
+	}
+	status, out = deliver(t, server.URL, "release", sign("s3cret", []byte(published)), published)
+	assert.Equal(t, 0, out.Queued)
+	assert.Empty(t, q.Jobs())
+
+	branch := `{"ref": "master", "ref_type": "branch", "repository": {"full_name": "johnpbloch/wordpress"}}`
+	status, out = deliver(t, server.URL, "create", sign("s3cret", []byte(branch)), branch)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ignored create event", out.Message)
+
+	status, _ = deliver(t, server.URL, "create", sign("s3cret", []byte(`nope`)), `nope`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	res, _ := http.Get(server.URL)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
+
+func TestJobRunner(t *testing.T) {
+	dir, _ := makeGitFixture("job")
+	fakeComposer(dir + "-bin")
+	tags := testTags("4.3", "4.2.3", "4.2.2", "4.2.1", "4.1.7")
+	results := []FleetResult{}
+	runner := JobRunner{
+		Fetch: func() (Tags, error) { return tags, nil },
+		Bumper: func(tags Tags, release string) FleetBumper {
+			return func(repo ManifestRepo) (bedrock.Result, error) {
+				return RunBump(bedrock.NewBedrock(repo.Path), tags, BumpOptions{
+					Release: release,
+					Policy:  Policy{Ignore: []string{"4.2.3"}},
+				})
+			}
+		},
+		Report: func(r FleetResult) { results = append(results, r) },
+	}
+
+	// the queued release is applied, not the newest one
+	assert.Nil(t, runner.Run(Job{Repo: ManifestRepo{Name: "a", Path: dir}, Version: "4.2.2"}))
+	assert.Equal(t, "4.2.2", bedrock.NewBedrock(dir).WordPressVersion())
+	assert.Equal(t, bedrock.StatusUpdated, results[0].Repos[0].Status)
+
+	err := runner.Run(Job{Repo: ManifestRepo{Name: "a", Path: dir}, Version: "4.2.3"})
+	assert.Equal(t, "WordPress 4.2.3 is rejected by policy: ignored by policy", err.Error())
+	assert.Equal(t, "4.2.2", bedrock.NewBedrock(dir).WordPressVersion())
+
+	// a backport to an older branch leaves the repository alone
+	assert.Nil(t, runner.Run(Job{Repo: ManifestRepo{Name: "a", Path: dir}, Version: "4.1.7"}))
+	assert.Equal(t, "4.2.2", bedrock.NewBedrock(dir).WordPressVersion())
+	assert.Equal(t, bedrock.StatusCurrent, results[len(results)-1].Repos[0].Status)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/bedrock"
+)
+
+// Status summarises the state of a Bedrock repository.
+type Status struct {
+	Repo                   string                  `json:"repo"`
+	ProjectVersion         string                  `json:"project_version"`
+	WordPressConstraint    string                  `json:"wordpress_constraint"`
+	LockedWordPressVersion string                  `json:"locked_wordpress_version,omitempty"`
+	LatestWordPressVersion string                  `json:"latest_wordpress_version"`
+	Unreleased             bool                    `json:"unreleased"`
+	ChangelogFormat        bedrock.ChangelogFormat `json:"changelog_format"`
+}
+
+// RepoStatus reads the status of b, with the newest release in tags.
+func RepoStatus(b bedrock.BedrockRepoInstance, tags Tags) (Status, error) {
+	s := Status{Repo: b.Path(), LatestWordPressVersion: tags.Latest()}
+	var err error
+	if s.WordPressConstraint, err = b.ReadWordPressVersion(); err != nil {
+		return s, err
+	}
+	if s.LockedWordPressVersion, err = b.LockedWordPressVersion(); err != nil {
+		return s, err
+	}
+	changelog, err := b.ReadChangelog()
+	if err != nil {
+		return s, err
+	}
+	if release, ok := changelog.Release(""); ok {
+		s.ProjectVersion = release.Version
+	}
+	_, s.Unreleased = changelog.Release(bedrock.Unreleased)
+	s.ChangelogFormat = changelog.Format()
+	return s, nil
+}
+
+// StatusText renders s for the terminal.
+func StatusText(s Status) string {
+	locked := s.LockedWordPressVersion
+	if locked == "" {
+		locked = "(no composer.lock)"
+	}
+	unreleased := "no"
+	if s.Unreleased {
+		unreleased = "yes"
+	}
+	format := s.ChangelogFormat.Style
+	if s.ChangelogFormat.DateLayout != "" {
+		format += ", dates " + s.ChangelogFormat.DateLayout
+	}
+	return fmt.Sprintf(`project version:      %s
+wordpress constraint: %s
+wordpress locked:     %s
+wordpress latest:     %s
+unreleased changes:   %s
+changelog format:     %s
+`, s.ProjectVersion, s.WordPressConstraint, locked, s.LatestWordPressVersion, unreleased, format)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"os/exec"
+	"testing"
+)
+
+func TestRepoStatus(t *testing.T) {
+	dir, _ := makeGitFixture("status")
+	exec.Command("cp", dir+"/CHANGELOG-head.md", dir+"/CHANGELOG.md").Run()
+
+	s, err := RepoStatus(bedrock.NewBedrock(dir), testTags("4.3-RC1", "4.2.3", "4.2.4"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Status{
+		Repo:                   dir,
+		ProjectVersion:         "1.3.6",
+		WordPressConstraint:    "4.2.1",
+		LatestWordPressVersion: "4.2.4",
+		Unreleased:             true,
+		ChangelogFormat:        bedrock.ChangelogFormat{Style: bedrock.StyleBedrock, DateLayout: "2006-01-02"},
+	}, s)
+	assert.Equal(t, `project version:      1.3.6
+wordpress constraint: 4.2.1
+wordpress locked:     (no composer.lock)
+wordpress latest:     4.2.4
+unreleased changes:   yes
+changelog format:     bedrock, dates 2006-01-02
+`, StatusText(s))
+}
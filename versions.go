@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"strings"
+	"time"
+)
+
+// Stabilities are the names accepted by --stability, least stable first.
+var Stabilities = []string{"dev", "alpha", "beta", "rc", "stable"}
+
+// ParseStability turns a stability name into a go-version stability.
+func ParseStability(name string) (int, error) {
+	for i, s := range Stabilities {
+		if strings.EqualFold(name, s) {
+			return i, nil
+		}
+	}
+	return 0, usageErrorf("unknown stability %q, expected one of %s", name, strings.Join(Stabilities, ", "))
+}
+
+// VersionFilter selects releases for the versions command. Since and Until
+// are inclusive; leaving both zero avoids fetching release dates.
+type VersionFilter struct {
+	Constraint   string
+	MinStability int
+	Since, Until time.Time
+}
+
+// VersionInfo is one release listed by the versions command.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Stability string `json:"stability"`
+	Date      string `json:"date,omitempty"`
+	Security  bool   `json:"security"`
+	Current   bool   `json:"current"`
+}
+
+// ListVersions lists the releases in tags matching filter, oldest first,
+// marking security releases and the current version.
+func ListVersions(tags Tags, filter VersionFilter, security SecurityReleases, current string) ([]VersionInfo, error) {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	version.Sort(names)
+
+	var constraint *version.ConstraintGroup
+	if filter.Constraint != "" {
+		constraint = version.NewConstrainGroupFromString(filter.Constraint)
+	}
+	byDate := !filter.Since.IsZero() || !filter.Until.IsZero()
+
+	list := []VersionInfo{}
+	for _, name := range names {
+		stability := version.GetStability(name)
+		if stability < filter.MinStability {
+			continue
+		}
+		if constraint != nil && !constraint.Match(name) {
+			continue
+		}
+		v := VersionInfo{
+			Version:   name,
+			Stability: Stabilities[stability],
+			Security:  security[name],
+			Current:   current != "" && version.Compare(name, current, "=="),
+		}
+		if byDate {
+			tag, _ := tags.Find(name)
+			released, err := ReleaseDate(tag)
+			if err != nil {
+				return nil, err
+			}
+			day := released.Format("2006-01-02")
+			if !filter.Since.IsZero() && day < filter.Since.Format("2006-01-02") ||
+				!filter.Until.IsZero() && day > filter.Until.Format("2006-01-02") {
+				continue
+			}
+			v.Date = day
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// VersionsText renders list one release per line.
+func VersionsText(list []VersionInfo) string {
+	text := ""
+	for _, v := range list {
+		line := fmt.Sprintf("%-12s", v.Version)
+		if v.Date != "" {
+			line += "  " + v.Date
+		}
+		if v.Stability != "stable" {
+			line += "  " + v.Stability
+		}
+		if v.Security {
+			line += "  security"
+		}
+		if v.Current {
+			line += "  (current)"
+		}
+		text += strings.TrimRight(line, " ") + "\n"
+	}
+	return text
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseStability(t *testing.T) {
+	s, err := ParseStability("RC")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, s)
+	_, err = ParseStability("nightly")
+	assert.NotNil(t, err)
+}
+
+func TestListVersions(t *testing.T) {
+	tags := testTags("4.3-beta1", "4.2.10", "4.2.2", "4.1.5", "4.2")
+	stable, _ := ParseStability("stable")
+
+	list, err := ListVersions(tags, VersionFilter{Constraint: "~4.2", MinStability: stable}, SecurityReleases{"4.2.2": true}, "4.2.2")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []VersionInfo{
+		{Version: "4.2", Stability: "stable"},
+		{Version: "4.2.2", Stability: "stable", Security: true, Current: true},
+		{Version: "4.2.10", Stability: "stable"},
+	}, list)
+	assert.Equal(t, "4.2\n4.2.2         security  (current)\n4.2.10\n", VersionsText(list))
+
+	beta, _ := ParseStability("beta")
+	list, _ = ListVersions(tags, VersionFilter{MinStability: beta}, nil, "")
+	assert.Equal(t, "4.3-beta1", list[len(list)-1].Version)
+	assert.Equal(t, "beta", list[len(list)-1].Stability)
+}
+
+func TestListVersionsByDate(t *testing.T) {
+	dates := map[string]string{"/4.2.1": "2015-04-27T12:00:00Z", "/4.2.2": "2015-05-07T12:00:00Z", "/4.2.3": "2015-07-23T12:00:00Z"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"commit": {"committer": {"date": %q}}}`, dates[r.URL.Path])
+	}))
+	defer ts.Close()
+	tags := Tags{}
+	for _, name := range []string{"4.2.3", "4.2.2", "4.2.1"} {
+		tags = append(tags, Tag{Name: name, Commit: Commit{Url: ts.URL + "/" + name}})
+	}
+
+	list, err := ListVersions(tags, VersionFilter{
+		Since: time.Date(2015, 5, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2015, 7, 23, 0, 0, 0, 0, time.UTC),
+	}, nil, "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []VersionInfo{
+		{Version: "4.2.2", Stability: "stable", Date: "2015-05-07"},
+		{Version: "4.2.3", Stability: "stable", Date: "2015-07-23"},
+	}, list)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/mcuadros/go-version"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// WatchState is what watch remembers between runs.
+type WatchState struct {
+	LastSeen  string    `json:"last_seen"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ReadWatchState reads the state file at path. A missing file is an empty
+// state.
+func ReadWatchState(path string) (WatchState, error) {
+	s := WatchState{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(data, &s)
+}
+
+// WriteWatchState atomically replaces the state file at path, so a crash
+// never leaves it half written.
+func WriteWatchState(path string, s WatchState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return bedrock.WriteFileAtomic(path, append(data, '\n'))
+}
+
+// DefaultMaxBackoff is the longest wait after failed polls when a Watcher
+// has no MaxBackoff.
+const DefaultMaxBackoff = 24 * time.Hour
+
+// Watcher polls for new WordPress releases and bumps a fleet when one
+// appears.
+type Watcher struct {
+	Fetch     func() (Tags, error)
+	Bump      func(Tags) FleetResult
+	StatePath string
+
+	// Interval is the time between polls, varied by up to Jitter either
+	// way. After failed polls the wait doubles each time, up to MaxBackoff.
+	Interval, Jitter, MaxBackoff time.Duration
+
+	Now    func() time.Time
+	After  func(time.Duration) <-chan time.Time
+	Random func() float64
+	Log    *log.Logger
+}
+
+// Poll checks for a release newer than the last one seen and bumps the
+// fleet when there is one. The state only moves forward once every
+// repository is on that release, so failed repositories, and ones whose
+// policy held the release back, are tried again next time.
+func (w Watcher) Poll() (bool, error) {
+	state, err := ReadWatchState(w.StatePath)
+	if err != nil {
+		return false, err
+	}
+	tags, err := w.Fetch()
+	if err != nil {
+		return false, err
+	}
+	versions := tags.Versions()
+	if len(versions) == 0 {
+		return false, nil
+	}
+	latest := versions[len(versions)-1]
+	state.CheckedAt = w.now()
+	if latest == state.LastSeen {
+		return false, WriteWatchState(w.StatePath, state)
+	}
+
+	w.logf("WordPress %s released, bumping", latest)
+	result := w.Bump(tags)
+	w.logf("%d updated, %d current, %d failed", result.Updated, result.Current, result.Failed)
+	if reached(result, latest) {
+		state.LastSeen = latest
+	}
+	return true, WriteWatchState(w.StatePath, state)
+}
+
+// Run polls until stop is closed.
+func (w Watcher) Run(stop <-chan struct{}) {
+	failures := 0
+	for {
+		if _, err := w.Poll(); err != nil {
+			failures++
+			w.logf("poll failed: %s", err)
+		} else {
+			failures = 0
+		}
+		after := time.After
+		if w.After != nil {
+			after = w.After
+		}
+		select {
+		case <-stop:
+			return
+		case <-after(w.Delay(failures)):
+		}
+	}
+}
+
+// Delay is the wait before the next poll after failures failed polls in a
+// row. Without a MaxBackoff the wait stops growing at DefaultMaxBackoff, or
+// at Interval when that is longer. It is never negative, even when Jitter
+// exceeds Interval.
+func (w Watcher) Delay(failures int) time.Duration {
+	limit := w.MaxBackoff
+	if limit == 0 {
+		limit = DefaultMaxBackoff
+		if w.Interval > limit {
+			limit = w.Interval
+		}
+	}
+	d := w.Interval
+	for i := 0; i < failures && d < limit; i++ {
+		if d > limit/2 {
+			d = limit
+		} else {
+			d *= 2
+		}
+	}
+	if w.Jitter > 0 {
+		random := rand.Float64
+		if w.Random != nil {
+			random = w.Random
+		}
+		jitter := time.Duration((random()*2 - 1) * float64(w.Jitter))
+		if jitter > 0 && d > math.MaxInt64-jitter {
+			jitter = math.MaxInt64 - d
+		}
+		d += jitter
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// reached reports whether every repository of result is on release v or
+// newer.
+func reached(result FleetResult, v string) bool {
+	if result.Failed > 0 {
+		return false
+	}
+	for _, repo := range result.Repos {
+		if version.Compare(repo.NewWordPressVersion, v, "<") {
+			return false
+		}
+	}
+	return true
+}
+
+func (w Watcher) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+func (w Watcher) logf(format string, v ...interface{}) {
+	if w.Log != nil {
+		w.Log.Printf(format, v...)
+	}
+}
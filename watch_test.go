@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"github.com/austinpray/bump-bedrock/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/austinpray/bump-bedrock/bedrock"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchState(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-watch")
+	defer os.RemoveAll(dir)
+	path := dir + "/state.json"
+
+	s, err := ReadWatchState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, WatchState{}, s)
+
+	checked := time.Date(2015, 5, 7, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, WriteWatchState(path, WatchState{LastSeen: "4.2.2", CheckedAt: checked}))
+	s, err = ReadWatchState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "4.2.2", s.LastSeen)
+	assert.True(t, checked.Equal(s.CheckedAt))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestWatcherPoll(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-watch")
+	defer os.RemoveAll(dir)
+	tags := testTags("4.2.1", "4.2.2", "4.3-beta1")
+	bumps, failed, reached := 0, 1, "4.2.1"
+	w := Watcher{
+		Fetch: func() (Tags, error) { return tags, nil },
+		Bump: func(Tags) FleetResult {
+			bumps++
+			repo := FleetRepo{Name: "a", Result: bedrock.Result{Status: bedrock.StatusCurrent, NewWordPressVersion: reached}}
+			return FleetResult{Repos: []FleetRepo{repo}, Failed: failed}
+		},
+		StatePath: dir + "/state.json",
+	}
+
+	// a failed repository is retried on the next poll
+	bumped, err := w.Poll()
+	assert.Nil(t, err)
+	assert.True(t, bumped)
+	s, _ := ReadWatchState(w.StatePath)
+	assert.Equal(t, "", s.LastSeen)
+	assert.False(t, s.CheckedAt.IsZero())
+
+	// so is one whose policy held the release back
+	failed = 0
+	bumped, err = w.Poll()
+	assert.Nil(t, err)
+	assert.True(t, bumped)
+	s, _ = ReadWatchState(w.StatePath)
+	assert.Equal(t, "", s.LastSeen)
+
+	reached = "4.2.2"
+	bumped, err = w.Poll()
+	assert.Nil(t, err)
+	assert.True(t, bumped)
+	s, _ = ReadWatchState(w.StatePath)
+	assert.Equal(t, "4.2.2", s.LastSeen)
+
+	// restarting does not bump the same release again
+	bumped, err = Watcher{Fetch: w.Fetch, Bump: w.Bump, StatePath: w.StatePath}.Poll()
+	assert.Nil(t, err)
+	assert.False(t, bumped)
+	assert.Equal(t, 3, bumps)
+
+	w.Fetch = func() (Tags, error) { return nil, &NetworkError{errors.New("offline")} }
+	_, err = w.Poll()
+	assert.Equal(t, "offline", err.Error())
+}
+
+func TestWatcherDelay(t *testing.T) {
+	w := Watcher{Interval: time.Hour, MaxBackoff: 5 * time.Hour}
+	assert.Equal(t, time.Hour, w.Delay(0))
+	assert.Equal(t, 2*time.Hour, w.Delay(1))
+	assert.Equal(t, 4*time.Hour, w.Delay(2))
+	assert.Equal(t, 5*time.Hour, w.Delay(3))
+	assert.Equal(t, 5*time.Hour, w.Delay(100))
+
+	w.Jitter = 10 * time.Minute
+	w.Random = func() float64 { return 0 }
+	assert.Equal(t, 50*time.Minute, w.Delay(0))
+	w.Random = func() float64 { return 1 }
+	assert.Equal(t, 70*time.Minute, w.Delay(0))
+
+	w.Jitter = 2 * time.Hour
+	w.Random = func() float64 { return 0 }
+	assert.Equal(t, time.Duration(0), w.Delay(0))
+
+	// without a maximum the wait still stops growing
+	w = Watcher{Interval: time.Hour}
+	assert.Equal(t, DefaultMaxBackoff, w.Delay(100))
+	w = Watcher{Interval: 48 * time.Hour}
+	assert.Equal(t, 48*time.Hour, w.Delay(100))
+	w = Watcher{Interval: time.Hour, MaxBackoff: time.Duration(math.MaxInt64)}
+	assert.Equal(t, time.Duration(math.MaxInt64), w.Delay(100))
+	w.Jitter, w.Random = time.Minute, func() float64 { return 1 }
+	assert.Equal(t, time.Duration(math.MaxInt64), w.Delay(100))
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bump-bedrock-watch")
+	defer os.RemoveAll(dir)
+	stop := make(chan struct{})
+	polls := 0
+	waits := []time.Duration{}
+	w := Watcher{
+		Fetch: func() (Tags, error) {
+			polls++
+			if polls < 3 {
+				return nil, errors.New("offline")
+			}
+			return testTags("4.2.2"), nil
+		},
+		Bump:      func(Tags) FleetResult { return FleetResult{} },
+		StatePath: dir + "/state.json",
+		Interval:  time.Minute,
+		After: func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			if len(waits) == 4 {
+				close(stop)
+			}
+			ch := make(chan time.Time, 1)
+			ch <- time.Time{}
+			return ch
+		},
+	}
+
+	w.Run(stop)
+
+	assert.Equal(t, []time.Duration{2 * time.Minute, 4 * time.Minute, time.Minute}, waits[:3])
+}